@@ -0,0 +1,100 @@
+/*
+Package main provides the backoff strategies used between request retries.
+A strategy only decides how long to wait, the scraper decides what to retry.
+*/
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+/*
+BackoffStrategy decides how long the scraper waits before retrying a request,
+after network errors, 5xx responses and 429 rate limit responses alike.
+*/
+type BackoffStrategy interface {
+	// NextDelay returns the wait before retry number attempt, counting from 0
+	NextDelay(attempt int) time.Duration
+}
+
+// ConstantBackoff waits the same delay before every retry
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+// NextDelay returns the constant delay
+func (b ConstantBackoff) NextDelay(attempt int) time.Duration {
+	return b.Delay
+}
+
+// LinearBackoff waits Base, then twice Base, three times Base and so on, at most Max
+type LinearBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// NextDelay returns Base times the number of the retry, capped at Max
+func (b LinearBackoff) NextDelay(attempt int) time.Duration {
+	return capDelay(b.Base*time.Duration(attempt+1), b.Max)
+}
+
+// ExponentialBackoff waits Base and doubles the delay for each further retry, at most Max
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// NextDelay returns Base doubled attempt times, capped at Max
+func (b ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	delay := b.Base
+	for i := 0; i < attempt; i++ {
+		delay *= 2
+		// Stop doubling once capped, before the duration can overflow
+		if b.Max > 0 && delay >= b.Max {
+			return b.Max
+		}
+	}
+	return capDelay(delay, b.Max)
+}
+
+// JitterBackoff waits a random delay up to that of ExponentialBackoff, so scrapers
+// failing at the same time don't all retry at the same time again
+type JitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// NextDelay returns a random delay between 0 and the exponential delay of the retry
+func (b JitterBackoff) NextDelay(attempt int) time.Duration {
+	limit := ExponentialBackoff{Base: b.Base, Max: b.Max}.NextDelay(attempt)
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(limit) + 1))
+}
+
+// capDelay limits a delay to max, a max of 0 means no limit
+func capDelay(delay, max time.Duration) time.Duration {
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
+
+// newBackoffStrategy returns the strategy with the given name, exponential when empty
+func newBackoffStrategy(name string, base, max time.Duration) (BackoffStrategy, error) {
+	switch strings.ToLower(name) {
+	case "constant":
+		return ConstantBackoff{Delay: base}, nil
+	case "linear":
+		return LinearBackoff{Base: base, Max: max}, nil
+	case "", "exponential":
+		return ExponentialBackoff{Base: base, Max: max}, nil
+	case "exponential_jitter":
+		return JitterBackoff{Base: base, Max: max}, nil
+	}
+	return nil, fmt.Errorf("unknown backoff_strategy %q, expected \"constant\", \"linear\", \"exponential\" or \"exponential_jitter\"", name)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy BackoffStrategy
+		want     []time.Duration
+	}{
+		{"constant", ConstantBackoff{Delay: time.Second}, []time.Duration{time.Second, time.Second, time.Second, time.Second}},
+		{"linear", LinearBackoff{Base: time.Second, Max: 3 * time.Second}, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}},
+		{"exponential", ExponentialBackoff{Base: time.Second}, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}},
+		{"exponential capped", ExponentialBackoff{Base: time.Second, Max: 5 * time.Second}, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for attempt, want := range tt.want {
+				if got := tt.strategy.NextDelay(attempt); got != want {
+					t.Errorf("NextDelay(%d) = %s, want %s", attempt, got, want)
+				}
+			}
+		})
+	}
+
+	if got := (ExponentialBackoff{Base: time.Second, Max: time.Minute}).NextDelay(100); got != time.Minute {
+		t.Errorf("NextDelay(100) = %s, want the cap without overflowing", got)
+	}
+}
+
+func TestJitterBackoff(t *testing.T) {
+	strategy := JitterBackoff{Base: time.Second, Max: 10 * time.Second}
+	for attempt := 0; attempt < 6; attempt++ {
+		limit := ExponentialBackoff{Base: time.Second, Max: 10 * time.Second}.NextDelay(attempt)
+		for i := 0; i < 50; i++ {
+			if got := strategy.NextDelay(attempt); got < 0 || got > limit {
+				t.Fatalf("NextDelay(%d) = %s, want between 0 and %s", attempt, got, limit)
+			}
+		}
+	}
+}
+
+func TestNewBackoffStrategy(t *testing.T) {
+	tests := []struct {
+		name string
+		want BackoffStrategy
+	}{
+		{"", ExponentialBackoff{Base: time.Second, Max: time.Minute}},
+		{"constant", ConstantBackoff{Delay: time.Second}},
+		{"Linear", LinearBackoff{Base: time.Second, Max: time.Minute}},
+		{"exponential", ExponentialBackoff{Base: time.Second, Max: time.Minute}},
+		{"exponential_jitter", JitterBackoff{Base: time.Second, Max: time.Minute}},
+	}
+	for _, tt := range tests {
+		got, err := newBackoffStrategy(tt.name, time.Second, time.Minute)
+		if err != nil || got != tt.want {
+			t.Errorf("newBackoffStrategy(%q) = %#v, %v, want %#v", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := newBackoffStrategy("fibonacci", time.Second, time.Minute); err == nil {
+		t.Error("newBackoffStrategy accepted an unknown strategy")
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Package main provides a conditional-request cache for search result pages.
+Pages are revalidated with ETag/Last-Modified so unchanged results are reused.
+*/
+package main
+
+import (
+	"net/http"
+	"sync"
+)
+
+// cacheEntry holds the validators and parsed items for a single URL
+type cacheEntry struct {
+	etag         string
+	lastModified string
+	items        []Item
+	listings     int
+}
+
+/*
+ResponseCache stores validators and parsed results per search URL.
+A cache should be shared only between scrapers with identical filters,
+since the stored items have already been filtered.
+*/
+type ResponseCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewResponseCache creates an empty response cache
+func NewResponseCache() *ResponseCache {
+	return &ResponseCache{entries: make(map[string]cacheEntry)}
+}
+
+// applyValidators adds conditional headers for a cached URL and reports whether an entry exists
+func (c *ResponseCache) applyValidators(req *http.Request) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[req.URL.String()]
+	if !ok {
+		return false
+	}
+	if entry.etag != "" {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+	if entry.lastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.lastModified)
+	}
+	return true
+}
+
+// cachedItems returns the stored items and unfiltered listing count for a URL
+func (c *ResponseCache) cachedItems(url string) ([]Item, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.entries[url]
+	return entry.items, entry.listings
+}
+
+// store saves the response validators and parsed items, if the response carried any validators
+func (c *ResponseCache) store(url string, resp *http.Response, items []Item, listings int) {
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[url] = cacheEntry{
+		etag:         etag,
+		lastModified: lastModified,
+		items:        items,
+		listings:     listings,
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Package main provides the adaptive concurrency controller of the worker pool.
+It lowers the number of searches scraped at the same time when eBay answers
+with rate limit or block responses and raises it again after sustained success.
+*/
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+)
+
+// Successful responses in a row before the concurrency is raised by one
+const concurrencyIncreaseAfter = 20
+
+/*
+ConcurrencyController limits how many searches run at the same time to a limit
+between min and max. Every 429 or 403 response lowers the limit by one, every
+concurrencyIncreaseAfter successful responses in a row raise it by one.
+Workers hold a slot while running a search. All methods are safe for concurrent
+use and do nothing on a nil ConcurrencyController, which doesn't limit anything.
+*/
+type ConcurrencyController struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	min       int
+	max       int
+	limit     int
+	active    int
+	successes int
+	metrics   *Metrics
+}
+
+// NewConcurrencyController creates a controller starting at start, kept between min and max
+func NewConcurrencyController(start, min, max int, metrics *Metrics) *ConcurrencyController {
+	if start < min {
+		start = min
+	}
+	if start > max {
+		start = max
+	}
+	c := &ConcurrencyController{min: min, max: max, limit: start, metrics: metrics}
+	c.cond = sync.NewCond(&c.mu)
+	metrics.setConcurrency(start)
+	log.Printf("Adaptive concurrency starts at %d searches at the same time, between %d and %d", start, min, max)
+	return c
+}
+
+// Acquire blocks until fewer searches run than the current limit and takes a slot
+func (c *ConcurrencyController) Acquire() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.active >= c.limit {
+		c.cond.Wait()
+	}
+	c.active++
+}
+
+// Release frees the slot taken by Acquire
+func (c *ConcurrencyController) Release() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.active--
+	c.mu.Unlock()
+	c.cond.Signal()
+}
+
+// Limit returns the current number of searches allowed to run at the same time
+func (c *ConcurrencyController) Limit() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limit
+}
+
+// observe adjusts the limit to the status code of a response from eBay
+func (c *ConcurrencyController) observe(code int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if code == http.StatusTooManyRequests || code == http.StatusForbidden {
+		c.successes = 0
+		if c.limit > c.min {
+			c.limit--
+			c.metrics.setConcurrency(c.limit)
+			log.Printf("eBay returned status %d, lowering the concurrency to %d", code, c.limit)
+		}
+		return
+	}
+	if code >= 400 {
+		return
+	}
+	c.successes++
+	if c.successes >= concurrencyIncreaseAfter && c.limit < c.max {
+		c.successes = 0
+		c.limit++
+		c.metrics.setConcurrency(c.limit)
+		log.Printf("%d successful requests in a row, raising the concurrency to %d", concurrencyIncreaseAfter, c.limit)
+		c.cond.Signal()
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyControllerAdapts(t *testing.T) {
+	c := NewConcurrencyController(3, 1, 4, nil)
+
+	c.observe(http.StatusTooManyRequests)
+	c.observe(http.StatusForbidden)
+	if got := c.Limit(); got != 1 {
+		t.Fatalf("limit after two block responses = %d, want 1", got)
+	}
+	c.observe(http.StatusTooManyRequests)
+	if got := c.Limit(); got != 1 {
+		t.Fatalf("limit went below the minimum: %d", got)
+	}
+
+	// A failure in between restarts the count of successes
+	for i := 0; i < concurrencyIncreaseAfter-1; i++ {
+		c.observe(http.StatusOK)
+	}
+	c.observe(http.StatusTooManyRequests)
+	for i := 0; i < concurrencyIncreaseAfter-1; i++ {
+		c.observe(http.StatusOK)
+	}
+	if got := c.Limit(); got != 1 {
+		t.Fatalf("limit raised before %d successes in a row: %d", concurrencyIncreaseAfter, got)
+	}
+	c.observe(http.StatusOK)
+	if got := c.Limit(); got != 2 {
+		t.Fatalf("limit after %d successes = %d, want 2", concurrencyIncreaseAfter, got)
+	}
+
+	// Other errors neither raise nor lower the limit
+	for i := 0; i < 10*concurrencyIncreaseAfter; i++ {
+		c.observe(http.StatusNotFound)
+	}
+	if got := c.Limit(); got != 2 {
+		t.Fatalf("limit changed on 404 responses: %d", got)
+	}
+
+	for i := 0; i < 10*concurrencyIncreaseAfter; i++ {
+		c.observe(http.StatusOK)
+	}
+	if got := c.Limit(); got != 4 {
+		t.Fatalf("limit went above the maximum: %d", got)
+	}
+}
+
+func TestConcurrencyControllerLimitsWorkers(t *testing.T) {
+	c := NewConcurrencyController(2, 1, 4, nil)
+
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				c.Acquire()
+				n := running.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				running.Add(-1)
+				c.Release()
+			}
+		}()
+	}
+	wg.Wait()
+	if got := peak.Load(); got > 2 {
+		t.Errorf("%d searches ran at the same time, want at most 2", got)
+	}
+
+	// A nil controller doesn't limit anything
+	var none *ConcurrencyController
+	none.Acquire()
+	none.observe(http.StatusTooManyRequests)
+	none.Release()
+}
@@ -0,0 +1,108 @@
+/*
+Package main provides currency detection and conversion for listing prices.
+Prices can be converted to a base currency so price filters compare listings
+from different marketplaces.
+*/
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+RateSource provides exchange rates for converting prices.
+StaticRates is the default; other sources, e.g. fetched daily rates, can be plugged in.
+*/
+type RateSource interface {
+	// Rate returns the value of one unit of from in units of to
+	Rate(from, to string) (float64, error)
+}
+
+/*
+StaticRates maps currency codes to the value of one unit in euros.
+*/
+type StaticRates map[string]float64
+
+// defaultRates are approximate rates used unless overridden by exchange_rates
+var defaultRates = StaticRates{
+	"EUR": 1,
+	"USD": 0.92,
+	"GBP": 1.17,
+	"CHF": 1.04,
+	"CAD": 0.68,
+	"AUD": 0.61,
+}
+
+// newStaticRates returns the default rates with the given rates replacing or adding entries
+func newStaticRates(overrides map[string]float64) StaticRates {
+	rates := make(StaticRates, len(defaultRates)+len(overrides))
+	for code, rate := range defaultRates {
+		rates[code] = rate
+	}
+	for code, rate := range overrides {
+		rates[strings.ToUpper(code)] = rate
+	}
+	return rates
+}
+
+// Rate returns the value of one unit of from in units of to
+func (r StaticRates) Rate(from, to string) (float64, error) {
+	fromRate, ok := r[from]
+	if !ok || fromRate <= 0 {
+		return 0, fmt.Errorf("no exchange rate for %q", from)
+	}
+	toRate, ok := r[to]
+	if !ok || toRate <= 0 {
+		return 0, fmt.Errorf("no exchange rate for %q", to)
+	}
+	return fromRate / toRate, nil
+}
+
+// Currency markers in price strings, checked in order so "AU $" isn't read as plain "$"
+var currencyMarkers = []struct {
+	marker string
+	code   string
+}{
+	{"AU $", "AUD"}, {"AU$", "AUD"}, {"AUD", "AUD"},
+	{"C $", "CAD"}, {"C$", "CAD"}, {"CAD", "CAD"},
+	{"US $", "USD"}, {"US$", "USD"}, {"USD", "USD"},
+	{"£", "GBP"}, {"GBP", "GBP"},
+	{"€", "EUR"}, {"EUR", "EUR"},
+	{"CHF", "CHF"},
+}
+
+// parseCurrency detects the currency of a price string, returning fallback, the
+// marketplace's currency, for prices without a marker or with a bare "$"
+func parseCurrency(price, fallback string) string {
+	for _, m := range currencyMarkers {
+		if strings.Contains(price, m.marker) {
+			return m.code
+		}
+	}
+	if strings.Contains(price, "$") && fallback != "AUD" && fallback != "CAD" {
+		return "USD"
+	}
+	return fallback
+}
+
+// convertPrice converts an amount with the given rate, keeping the -1 marker for unknown amounts
+func convertPrice(amount, rate float64) float64 {
+	if amount < 0 {
+		return amount
+	}
+	return amount * rate
+}
+
+// exchangeRate returns the rate converting prices in currency to the base currency
+// and the resulting currency. Without a base currency or a known rate prices stay as they are.
+func (s *Scraper) exchangeRate(currency string) (float64, string) {
+	if s.BaseCurrency == "" || s.Rates == nil || currency == "" || currency == s.BaseCurrency {
+		return 1, currency
+	}
+	rate, err := s.Rates.Rate(currency, s.BaseCurrency)
+	if err != nil {
+		return 1, currency
+	}
+	return rate, s.BaseCurrency
+}
@@ -0,0 +1,201 @@
+/*
+Package main provides storage and housekeeping for the daily logs.
+Daily logs are rewritten atomically on every save, compressed with gzip
+once they are old enough and deleted after the retention period.
+*/
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Matches the daily log and summary files with the day they belong to
+var dailyFileRe = regexp.MustCompile(`^(?:findings|summary)_(\d{4}-\d{2}-\d{2})\.(?:json|json\.gz|md)$`)
+
+// appendDailyLog adds records to today's log by writing the old content and the new
+// records to a temp file that replaces the log, so a crash never leaves a partial record
+func appendDailyLog(items []SavedItem) error {
+	if err := os.MkdirAll("logs", 0755); err != nil {
+		return err
+	}
+	path := dailyLogPath(time.Now())
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(existing); err != nil {
+		tmp.Close()
+		return err
+	}
+	encoder := json.NewEncoder(tmp)
+	for _, saved := range items {
+		if err := encoder.Encode(saved); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readDailyLog reads the log of a day, whether or not it was compressed yet
+func readDailyLog(day time.Time) ([]SavedItem, error) {
+	path := dailyLogPath(day)
+	findings, err := readFindings(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return readFindings(path + ".gz")
+	}
+	return findings, err
+}
+
+// updateDailyLog replaces the records in the log of a day with those returned by update,
+// writing a temp file that replaces the log. A compressed log stays compressed.
+func updateDailyLog(day time.Time, update func([]SavedItem) []SavedItem) error {
+	if err := os.MkdirAll("logs", 0755); err != nil {
+		return err
+	}
+	path := dailyLogPath(day)
+	_, statErr := os.Stat(path)
+	compressed := false
+	if errors.Is(statErr, os.ErrNotExist) {
+		if _, err := os.Stat(path + ".gz"); err == nil {
+			compressed = true
+		}
+	}
+
+	findings, err := readDailyLog(day)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	findings = update(findings)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	encoder := json.NewEncoder(tmp)
+	for _, saved := range findings {
+		if err := encoder.Encode(saved); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if compressed {
+		return compressFile(path)
+	}
+	return nil
+}
+
+// compressFile replaces a file with a gzip-compressed copy named path + ".gz"
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".gz.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	if _, err := io.Copy(gz, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// rotateDailyLogs compresses daily logs older than compressAfterDays and deletes daily
+// logs and summaries older than retentionDays. Zero disables either step. It returns
+// how many files were compressed and deleted.
+func rotateDailyLogs(compressAfterDays, retentionDays int, now time.Time) (int, int, error) {
+	entries, err := os.ReadDir("logs")
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	compressed, deleted := 0, 0
+	var errs []error
+	for _, entry := range entries {
+		matches := dailyFileRe.FindStringSubmatch(entry.Name())
+		if matches == nil || entry.IsDir() {
+			continue
+		}
+		day, err := time.ParseInLocation("2006-01-02", matches[1], time.Local)
+		if err != nil {
+			continue
+		}
+		age := int(today.Sub(day).Hours()/24 + 0.5)
+		path := filepath.Join("logs", entry.Name())
+
+		switch {
+		case retentionDays > 0 && age > retentionDays:
+			if err := os.Remove(path); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			deleted++
+		case compressAfterDays > 0 && age > compressAfterDays && filepath.Ext(path) == ".json":
+			if err := compressFile(path); err != nil {
+				errs = append(errs, fmt.Errorf("compressing %s: %w", path, err))
+				continue
+			}
+			compressed++
+		}
+	}
+	return compressed, deleted, errors.Join(errs...)
+}
@@ -0,0 +1,176 @@
+/*
+Package main provides email notifications over SMTP.
+Each new item or price drop is sent as a short HTML email with a link to the listing.
+*/
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+EmailConfig holds the SMTP settings for email notifications.
+Security is "starttls" (default), "tls" for implicit TLS as on port 465,
+or "none" for unencrypted servers, e.g. a local relay. Without Username
+no authentication is attempted.
+*/
+type EmailConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Security string   `json:"security,omitempty"`
+}
+
+// validate checks that the settings needed to send an email are present
+func (e *EmailConfig) validate() error {
+	var problems []error
+	if e.Host == "" {
+		problems = append(problems, errors.New("email: host is empty"))
+	}
+	if e.From == "" {
+		problems = append(problems, errors.New("email: from is empty"))
+	}
+	if len(e.To) == 0 {
+		problems = append(problems, errors.New("email: no to addresses"))
+	}
+	switch e.Security {
+	case "", "starttls", "tls", "none":
+	default:
+		problems = append(problems, fmt.Errorf("email: security %q, expected \"starttls\", \"tls\" or \"none\"", e.Security))
+	}
+	return errors.Join(problems...)
+}
+
+// addr returns the server address, using the default port of the security mode if none is set
+func (e *EmailConfig) addr() string {
+	port := e.Port
+	if port == 0 {
+		switch e.Security {
+		case "tls":
+			port = 465
+		case "none":
+			port = 25
+		default:
+			port = 587
+		}
+	}
+	return net.JoinHostPort(e.Host, strconv.Itoa(port))
+}
+
+/*
+EmailNotifier sends an HTML email for each new item, price drop or shipping change
+through an SMTP server, rendered with the email templates, the built-in ones if Templates is nil.
+*/
+type EmailNotifier struct {
+	Config    *EmailConfig
+	Templates *notificationTemplates
+}
+
+// Notify sends an email about a new item to all configured recipients
+func (e *EmailNotifier) Notify(item Item, query string) error {
+	return e.sendItem(newSavedItem(item, query))
+}
+
+// NotifyChange sends an email about a price drop or shipping change to all configured recipients
+func (e *EmailNotifier) NotifyChange(saved SavedItem) error {
+	return e.sendItem(saved)
+}
+
+// sendItem sends an email describing the entry
+func (e *EmailNotifier) sendItem(saved SavedItem) error {
+	templates := e.Templates
+	if templates == nil {
+		templates = builtinTemplates
+	}
+	var body bytes.Buffer
+	if err := templates.Email.Execute(&body, saved); err != nil {
+		return err
+	}
+	subject, err := executeTemplate(templates.EmailSubject, saved)
+	if err != nil {
+		return err
+	}
+	// A line break would end the header early
+	return e.send(strings.Join(strings.Fields(subject), " "), body.Bytes())
+}
+
+// send delivers an HTML message, applying the configured TLS mode and authentication
+func (e *EmailNotifier) send(subject string, html []byte) error {
+	cfg := e.Config
+	addr := cfg.addr()
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if cfg.Security == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if cfg.Security == "" || cfg.Security == "starttls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, to := range cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+	msg.Write(html)
+
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
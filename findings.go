@@ -0,0 +1,555 @@
+/*
+Package main provides tools for working with saved findings files.
+findings.json holds a JSON array of SavedItem records, while the daily logs
+are a stream of JSON-encoded records. Findings can be mirrored to a CSV file
+for spreadsheets.
+*/
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvHeader lists the columns written by saveCSV
+var csvHeader = []string{"title", "price_value", "url", "is_auction", "watchers", "time_left", "query", "found"}
+
+// readFindings decodes all SavedItem records from a findings file, either a JSON array
+// or a stream of records as written to the daily logs and by earlier versions.
+// Files ending in .gz are decompressed.
+func readFindings(path string) ([]SavedItem, error) {
+	var findings []SavedItem
+	err := eachFinding(path, func(saved SavedItem) error {
+		findings = append(findings, saved)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// eachFinding decodes the records of a findings file one at a time and passes them to fn,
+// so large files are never held in memory as a whole. It stops at the first error of fn.
+func eachFinding(path string, fn func(SavedItem) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var input io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		defer gz.Close()
+		input = gz
+	}
+
+	reader := bufio.NewReader(input)
+	array := isJSONArray(reader)
+	decoder := json.NewDecoder(reader)
+	if array {
+		// Consume the opening bracket so the records can be decoded one by one
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for !array || decoder.More() {
+		var saved SavedItem
+		err := decoder.Decode(&saved)
+		if !array && errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := fn(saved); err != nil {
+			return err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// isJSONArray reports whether the next non-whitespace byte opens a JSON array,
+// leaving the reader positioned at it
+func isJSONArray(reader *bufio.Reader) bool {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		reader.UnreadByte()
+		return b == '['
+	}
+}
+
+/*
+seenItem is the last known price and shipping cost of an item and when it last showed up.
+Items loaded from saved findings count as last seen when they were saved.
+*/
+type seenItem struct {
+	Price    float64
+	Shipping float64 // -1 if unknown
+	LastSeen time.Time
+}
+
+// expireSeenItems forgets items that haven't shown up for longer than ttl,
+// so they are reported as new if they appear again, and returns how many were removed
+func expireSeenItems(seen map[string]seenItem, ttl time.Duration, now time.Time) int {
+	expired := 0
+	for key, entry := range seen {
+		if now.Sub(entry.LastSeen) > ttl {
+			delete(seen, key)
+			expired++
+		}
+	}
+	return expired
+}
+
+// loadSeenItems reads findings.json and returns the last recorded price of the
+// seen items per query. A missing file means nothing has been seen yet.
+func loadSeenItems() (map[string]map[string]seenItem, error) {
+	seenItems := make(map[string]map[string]seenItem)
+
+	err := eachFinding("findings.json", func(saved SavedItem) error {
+		if seenItems[saved.QueryTerm] == nil {
+			seenItems[saved.QueryTerm] = make(map[string]seenItem)
+		}
+		seenItems[saved.QueryTerm][parseItemID(saved.Item.URL)] = seenItem{Price: saved.Item.PriceValue, Shipping: saved.Item.ShippingCost, LastSeen: saved.Found}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return seenItems, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return seenItems, nil
+}
+
+// findingsByKey reads a findings file one record at a time and indexes the records by
+// their dedup key, keeping the latest record for each, so repeated records of the same
+// item don't pile up in memory
+func findingsByKey(path string) (map[string]SavedItem, error) {
+	byKey := make(map[string]SavedItem)
+	err := eachFinding(path, func(saved SavedItem) error {
+		byKey[parseItemID(saved.Item.URL)] = saved
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return byKey, nil
+}
+
+// sortedKeys returns the map keys in a stable order for printing
+func sortedKeys(items map[string]SavedItem) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diffFindings prints the items added, removed and changed in price between two findings files
+func diffFindings(oldPath, newPath string) error {
+	oldItems, err := findingsByKey(oldPath)
+	if err != nil {
+		return err
+	}
+	newItems, err := findingsByKey(newPath)
+	if err != nil {
+		return err
+	}
+
+	var added, removed, changed []string
+	for _, key := range sortedKeys(newItems) {
+		old, ok := oldItems[key]
+		if !ok {
+			added = append(added, key)
+		} else if toCents(old.Item.PriceValue) != toCents(newItems[key].Item.PriceValue) {
+			changed = append(changed, key)
+		}
+	}
+	for _, key := range sortedKeys(oldItems) {
+		if _, ok := newItems[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+
+	headerColor.Printf("Added (%d):\n", len(added))
+	for _, key := range added {
+		saved := newItems[key]
+		fmt.Printf("  + %-10.2f %-20s %s\n", saved.Item.PriceValue, saved.QueryTerm, saved.Item.Title)
+	}
+	headerColor.Printf("\nRemoved (%d):\n", len(removed))
+	for _, key := range removed {
+		saved := oldItems[key]
+		fmt.Printf("  - %-10.2f %-20s %s\n", saved.Item.PriceValue, saved.QueryTerm, saved.Item.Title)
+	}
+	headerColor.Printf("\nPrice changed (%d):\n", len(changed))
+	for _, key := range changed {
+		old, saved := oldItems[key], newItems[key]
+		fmt.Printf("  ~ %.2f -> %.2f  %-20s %s\n", old.Item.PriceValue, saved.Item.PriceValue, saved.QueryTerm, saved.Item.Title)
+	}
+	return nil
+}
+
+// writeFindings atomically replaces a findings file with a JSON array of the given records,
+// indented when pretty is set
+func writeFindings(path string, findings []SavedItem, pretty bool) error {
+	return writeFindingsArray(path, pretty, func(write func(SavedItem) error) error {
+		for _, saved := range findings {
+			if err := write(saved); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// appendFindings adds records to the array in a findings file, rewriting it atomically
+// so the file stays valid JSON. The existing records are copied one at a time rather
+// than loaded as a whole. A file in the older stream format is converted.
+func appendFindings(path string, items []SavedItem, pretty bool) error {
+	return writeFindingsArray(path, pretty, func(write func(SavedItem) error) error {
+		if err := eachFinding(path, write); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		for _, saved := range items {
+			if err := write(saved); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeFindingsArray atomically replaces a findings file with a JSON array of the records
+// passed to write by produce, encoding them as they come. The file is readable by everyone
+// like the other output files.
+func writeFindingsArray(path string, pretty bool, produce func(write func(SavedItem) error) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	out := bufio.NewWriter(tmp)
+	out.WriteString("[")
+	count := 0
+	write := func(saved SavedItem) error {
+		var data []byte
+		var err error
+		if pretty {
+			data, err = json.MarshalIndent(saved, "    ", "    ")
+		} else {
+			data, err = json.Marshal(saved)
+		}
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			out.WriteString(",")
+		}
+		if pretty {
+			out.WriteString("\n    ")
+		}
+		count++
+		_, err = out.Write(data)
+		return err
+	}
+	if err := produce(write); err != nil {
+		tmp.Close()
+		return err
+	}
+	if pretty && count > 0 {
+		out.WriteString("\n")
+	}
+	out.WriteString("]\n")
+
+	if err := out.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Runs of characters that aren't safe in file names on all platforms
+var unsafeFilenameRe = regexp.MustCompile(`[^\p{L}\p{N}._-]+`)
+
+// sanitizeFilename turns a query into a safe file name without extension,
+// e.g. "Nintendo Switch / OLED" becomes "nintendo-switch-oled"
+func sanitizeFilename(query string) string {
+	name := unsafeFilenameRe.ReplaceAllString(strings.ToLower(query), "-")
+	name = strings.Trim(name, "-.")
+	if runes := []rune(name); len(runes) > 100 {
+		name = strings.TrimRight(string(runes[:100]), "-.")
+	}
+	if name == "" {
+		return "query"
+	}
+	return name
+}
+
+// appendQueryFindings adds records to the query's own file in the findings directory
+func appendQueryFindings(query string, items []SavedItem, pretty bool) error {
+	if err := os.MkdirAll("findings", 0755); err != nil {
+		return err
+	}
+	return appendFindings(filepath.Join("findings", sanitizeFilename(query)+".json"), items, pretty)
+}
+
+// importKey identifies the history of an item within a query, as the seen state does
+func importKey(saved SavedItem) string {
+	return saved.QueryTerm + "\x00" + parseItemID(saved.Item.URL)
+}
+
+// recordKey identifies a single record of an item's history
+func recordKey(saved SavedItem) string {
+	return importKey(saved) + "\x00" + strconv.FormatInt(saved.Found.UnixNano(), 10)
+}
+
+// mergeHistory merges the records of one item from both instances into a single history.
+// The earliest record becomes the first sighting, every later record with a different
+// price or shipping cost is kept as a change and records repeating the last ones are dropped.
+func mergeHistory(records []SavedItem) []SavedItem {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Found.Before(records[j].Found)
+	})
+	merged := []SavedItem{records[0]}
+	merged[0].PreviousPrice = 0
+	merged[0].PreviousShipping = nil
+	for _, saved := range records[1:] {
+		last := merged[len(merged)-1]
+		samePrice := toCents(saved.Item.PriceValue) == toCents(last.Item.PriceValue)
+		sameShipping := toCents(saved.Item.ShippingCost) == toCents(last.Item.ShippingCost)
+		if samePrice && sameShipping {
+			continue
+		}
+		// A first sighting of the other instance becomes a change from the record seen before it
+		if saved.isNew() {
+			if !samePrice {
+				saved.PreviousPrice = last.Item.PriceValue
+			}
+			if !sameShipping {
+				previousShipping := last.Item.ShippingCost
+				saved.PreviousShipping = &previousShipping
+			}
+		}
+		merged = append(merged, saved)
+	}
+	return merged
+}
+
+// sameChanges reports whether two records of the same sighting record the same changes
+func sameChanges(a, b SavedItem) bool {
+	if a.PreviousPrice != b.PreviousPrice || (a.PreviousShipping == nil) != (b.PreviousShipping == nil) {
+		return false
+	}
+	return a.PreviousShipping == nil || *a.PreviousShipping == *b.PreviousShipping
+}
+
+/*
+importFindings merges another findings file into the local one, for example when moving the
+monitor to another machine. The records of each item are merged by item id within their
+query: the earliest record is kept as the first sighting, later records with another price
+or shipping cost as changes. Records the merge adds or changes are written to the daily logs of the
+days they were found on, so the seen state restored from the logs and the reports include
+them, and findings.json holds the seen state of the next start.
+*/
+func importFindings(localPath, importPath string) error {
+	local, err := readFindings(localPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// Group the records by item, keeping the order in which the items were first found
+	var keys []string
+	histories := make(map[string][]SavedItem)
+	collect := func(saved SavedItem) error {
+		key := importKey(saved)
+		if _, ok := histories[key]; !ok {
+			keys = append(keys, key)
+		}
+		histories[key] = append(histories[key], saved)
+		return nil
+	}
+	for _, saved := range local {
+		collect(saved)
+	}
+	imported := 0
+	err = eachFinding(importPath, func(saved SavedItem) error {
+		imported++
+		return collect(saved)
+	})
+	if err != nil {
+		return err
+	}
+
+	var merged []SavedItem
+	for _, key := range keys {
+		merged = append(merged, mergeHistory(histories[key])...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Found.Before(merged[j].Found)
+	})
+
+	// Compare the records before and after the merge to update the daily logs
+	before := make(map[string]SavedItem, len(local))
+	for _, saved := range local {
+		before[recordKey(saved)] = saved
+	}
+	after := make(map[string]bool, len(merged))
+	removed := make(map[string]bool)
+	changedDays := make(map[string]time.Time)
+	added := make(map[string][]SavedItem)
+	newItems, changes, updated := 0, 0, 0
+	for _, saved := range merged {
+		key := recordKey(saved)
+		after[key] = true
+		old, ok := before[key]
+		if ok && sameChanges(old, saved) {
+			continue
+		}
+		if ok {
+			removed[key] = true
+			updated++
+		} else if saved.isNew() {
+			newItems++
+		} else {
+			changes++
+		}
+		day := saved.Found.Local().Format("2006-01-02")
+		changedDays[day] = saved.Found.Local()
+		added[day] = append(added[day], saved)
+	}
+	for key, saved := range before {
+		if !after[key] {
+			removed[key] = true
+			changedDays[saved.Found.Local().Format("2006-01-02")] = saved.Found.Local()
+		}
+	}
+
+	if err := writeFindings(localPath, merged, false); err != nil {
+		return err
+	}
+	for day, date := range changedDays {
+		err := updateDailyLog(date, func(findings []SavedItem) []SavedItem {
+			kept := findings[:0]
+			for _, saved := range findings {
+				if !removed[recordKey(saved)] {
+					kept = append(kept, saved)
+				}
+			}
+			return append(kept, added[day]...)
+		})
+		if err != nil {
+			log.Printf("Error updating the daily log of %s: %v", day, err)
+		}
+	}
+
+	headerColor.Printf("Imported %d records from %s: %d new items, %d changes, %d duplicates merged, %d local records became changes\n",
+		imported, importPath, newItems, changes, len(local)+imported-len(merged), updated)
+	return nil
+}
+
+// rehydrateSeenItems marks items from the daily logs of the last days (including today)
+// as seen for the configured queries, keeping their most recent price, and returns
+// how many were restored
+func rehydrateSeenItems(days int, seenItems map[string]map[string]seenItem) int {
+	restored := 0
+	now := time.Now()
+	// Oldest day first so later entries, e.g. price drops, leave the latest price
+	for offset := days - 1; offset >= 0; offset-- {
+		findings, err := readDailyLog(now.AddDate(0, 0, -offset))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error reading daily log: %v", err)
+		}
+		for _, saved := range findings {
+			seen, ok := seenItems[saved.QueryTerm]
+			if !ok {
+				continue
+			}
+			key := parseItemID(saved.Item.URL)
+			if _, known := seen[key]; !known {
+				restored++
+			}
+			seen[key] = seenItem{Price: saved.Item.PriceValue, Shipping: saved.Item.ShippingCost, LastSeen: saved.Found}
+		}
+	}
+	return restored
+}
+
+// saveCSV appends items to the CSV file at path, writing the header row
+// only when the file is new or empty
+func saveCSV(items []SavedItem, path string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	if info.Size() == 0 {
+		if err := writer.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	for _, saved := range items {
+		record := []string{
+			saved.Item.Title,
+			strconv.FormatFloat(saved.Item.PriceValue, 'f', 2, 64),
+			saved.Item.URL,
+			strconv.FormatBool(saved.Item.IsAuction),
+			strconv.Itoa(saved.Item.Watchers),
+			saved.Item.TimeLeft,
+			saved.QueryTerm,
+			saved.Found.Format(time.RFC3339),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFindings returns a few records spread over two queries
+func testFindings() []SavedItem {
+	found := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []SavedItem{
+		{Item: Item{ID: "1", Title: "Switch OLED", PriceValue: 249.99, URL: "https://www.ebay.de/itm/1"}, Found: found, QueryTerm: "switch"},
+		{Item: Item{ID: "2", Title: "LEGO 42115", PriceValue: 320, URL: "https://www.ebay.de/itm/2"}, Found: found.Add(time.Hour), QueryTerm: "lego"},
+		{Item: Item{ID: "1", Title: "Switch OLED", PriceValue: 229.99, URL: "https://www.ebay.de/itm/1"}, Found: found.Add(2 * time.Hour), QueryTerm: "switch", PreviousPrice: 249.99},
+	}
+}
+
+func TestFindingsArrayRoundTrip(t *testing.T) {
+	for _, pretty := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "findings.json")
+		findings := testFindings()
+		if err := appendFindings(path, findings[:1], pretty); err != nil {
+			t.Fatalf("appendFindings to a new file: %v", err)
+		}
+		if err := appendFindings(path, findings[1:], pretty); err != nil {
+			t.Fatalf("appendFindings to an existing file: %v", err)
+		}
+
+		// The file is a plain JSON array for any tool, not only for eachFinding
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded []SavedItem
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("pretty=%v: findings.json is not a JSON array: %v\n%s", pretty, err, data)
+		}
+		if !reflect.DeepEqual(decoded, findings) {
+			t.Errorf("pretty=%v: decoded %+v, want %+v", pretty, decoded, findings)
+		}
+		read, err := readFindings(path)
+		if err != nil || !reflect.DeepEqual(read, findings) {
+			t.Errorf("pretty=%v: readFindings = %+v, %v, want %+v", pretty, read, err, findings)
+		}
+
+		// Same bytes as encoding/json, so rewriting the whole file doesn't change its format
+		var want []byte
+		if pretty {
+			want, _ = json.MarshalIndent(findings, "", "    ")
+		} else {
+			want, _ = json.Marshal(findings)
+		}
+		if string(data) != string(want)+"\n" {
+			t.Errorf("pretty=%v: file content differs from encoding/json:\n%s\nwant:\n%s", pretty, data, want)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0644 {
+			t.Errorf("pretty=%v: file mode = %v, want 0644", pretty, perm)
+		}
+		if leftovers, _ := filepath.Glob(path + ".tmp*"); len(leftovers) > 0 {
+			t.Errorf("pretty=%v: temporary files left behind: %v", pretty, leftovers)
+		}
+	}
+}
+
+func TestEachFindingStreamFormat(t *testing.T) {
+	// Earlier versions and the daily logs write one record after another
+	findings := testFindings()
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	for _, saved := range findings {
+		encoder.Encode(saved)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "findings.json")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	read, err := readFindings(path)
+	if err != nil || !reflect.DeepEqual(read, findings) {
+		t.Errorf("readFindings of a stream = %+v, %v, want %+v", read, err, findings)
+	}
+
+	gzPath := filepath.Join(dir, "findings_2024-05-01.json.gz")
+	file, err := os.Create(gzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte(b.String()))
+	gz.Close()
+	file.Close()
+	read, err = readFindings(gzPath)
+	if err != nil || !reflect.DeepEqual(read, findings) {
+		t.Errorf("readFindings of a compressed stream = %+v, %v, want %+v", read, err, findings)
+	}
+
+	// Appending converts the stream into an array
+	extra := SavedItem{Item: Item{ID: "3", Title: "Game Boy", URL: "https://www.ebay.de/itm/3"}, Found: findings[0].Found, QueryTerm: "gameboy"}
+	if err := appendFindings(path, []SavedItem{extra}, false); err != nil {
+		t.Fatalf("appendFindings to a stream: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	var decoded []SavedItem
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("converted file is not a JSON array: %v", err)
+	}
+	if want := append(findings, extra); !reflect.DeepEqual(decoded, want) {
+		t.Errorf("converted file = %+v, want %+v", decoded, want)
+	}
+}
+
+func TestEachFindingStopsOnCallbackError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findings.json")
+	if err := writeFindings(path, testFindings(), false); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	stop := os.ErrClosed
+	err := eachFinding(path, func(SavedItem) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("eachFinding = %v after %d calls, want the callback's error after 1", err, calls)
+	}
+}
+
+func TestImportFindingsOverlap(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	day := func(offset int) time.Time {
+		return time.Now().Truncate(time.Second).AddDate(0, 0, offset)
+	}
+	record := func(id string, price float64, found time.Time, previous float64) SavedItem {
+		return SavedItem{
+			Item:          Item{ID: id, Title: "Item " + id, PriceValue: price, URL: "https://www.ebay.de/itm/" + id},
+			Found:         found,
+			QueryTerm:     "switch",
+			PreviousPrice: previous,
+		}
+	}
+	local := []SavedItem{
+		record("1", 250, day(-2), 0),
+		record("2", 320, day(-1), 0),
+		record("1", 230, day(0), 250),
+	}
+	imported := []SavedItem{
+		// Found earlier at another price by the other instance
+		record("1", 260, day(-3), 0),
+		record("3", 99, day(-1), 0),
+		// The same price drop seen by both instances
+		record("1", 230, day(0), 250),
+	}
+	if err := writeFindings("findings.json", local, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendDailyLog(local[2:]); err != nil {
+		t.Fatal(err)
+	}
+	for _, saved := range local[:2] {
+		if err := updateDailyLog(saved.Found, func(findings []SavedItem) []SavedItem {
+			return append(findings, saved)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Old logs may be compressed already
+	if err := compressFile(dailyLogPath(day(-2))); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFindings("other.json", imported, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := importFindings("findings.json", "other.json"); err != nil {
+		t.Fatalf("importFindings: %v", err)
+	}
+
+	type entry struct {
+		ID       string
+		Price    float64
+		Previous float64
+	}
+	entries := func(findings []SavedItem) []entry {
+		var got []entry
+		for _, saved := range findings {
+			got = append(got, entry{saved.Item.ID, saved.Item.PriceValue, saved.PreviousPrice})
+		}
+		return got
+	}
+
+	merged, err := readFindings("findings.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []entry{{"1", 260, 0}, {"1", 250, 260}, {"2", 320, 0}, {"3", 99, 0}, {"1", 230, 250}}
+	if got := entries(merged); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged findings = %v, want %v", got, want)
+	}
+
+	// The daily logs hold the merged history of their day
+	for offset, want := range map[int][]entry{
+		-3: {{"1", 260, 0}},
+		-2: {{"1", 250, 260}},
+		-1: {{"2", 320, 0}, {"3", 99, 0}},
+		0:  {{"1", 230, 250}},
+	} {
+		findings, err := readDailyLog(day(offset))
+		if err != nil {
+			t.Fatalf("daily log of day %d: %v", offset, err)
+		}
+		if got := entries(findings); !reflect.DeepEqual(got, want) {
+			t.Errorf("daily log of day %d = %v, want %v", offset, got, want)
+		}
+	}
+	if _, err := os.Stat(dailyLogPath(day(-2)) + ".gz"); err != nil {
+		t.Errorf("compressed daily log was not kept compressed: %v", err)
+	}
+
+	seenItems, err := loadSeenItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen := seenItems["switch"]["1"]; seen.Price != 230 || !seen.LastSeen.Equal(day(0)) {
+		t.Errorf("seen state of item 1 = %+v, want the price drop of today", seen)
+	}
+	if _, ok := seenItems["switch"]["3"]; !ok {
+		t.Error("imported item 3 is not in the seen state")
+	}
+
+	report, err := generateReport(day(-3), day(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Queries) != 1 || report.Queries[0].Items != 3 || report.Queries[0].PriceDrops != 2 {
+		t.Errorf("report = %+v, want 3 items and 2 price drops", report.Queries)
+	}
+}
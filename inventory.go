@@ -0,0 +1,112 @@
+/*
+Package main provides tracking of how many listings match each query over time.
+The history is stored in inventory.json and can be printed with -inventory.
+*/
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const inventoryFile = "inventory.json"
+
+/*
+InventoryPoint records the number of matching listings for a query at a point in time.
+Points are only recorded when the count changes by at least the configured threshold.
+*/
+type InventoryPoint struct {
+	Time  time.Time `json:"time"`
+	Count int       `json:"count"`
+}
+
+// loadInventory reads the inventory history, returning an empty history if none exists yet
+func loadInventory() (map[string][]InventoryPoint, error) {
+	history := make(map[string][]InventoryPoint)
+	data, err := os.ReadFile(inventoryFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// saveInventory writes the inventory history to inventory.json, replacing the file
+// atomically so a crash mid-write can't leave a truncated history behind
+func saveInventory(history map[string][]InventoryPoint) error {
+	data, err := json.MarshalIndent(history, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(inventoryFile), filepath.Base(inventoryFile)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), inventoryFile)
+}
+
+// recordInventory appends a point when the count moved by at least threshold since the
+// last recorded point. It returns the previous count and whether a point was added.
+func recordInventory(history map[string][]InventoryPoint, query string, count int, threshold int) (int, bool) {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	points := history[query]
+	previous := -1
+	if len(points) > 0 {
+		previous = points[len(points)-1].Count
+		diff := count - previous
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff < threshold {
+			return previous, false
+		}
+	}
+	history[query] = append(points, InventoryPoint{Time: time.Now(), Count: count})
+	return previous, true
+}
+
+// printInventory displays the recorded inventory history for a query
+func printInventory(query string) error {
+	history, err := loadInventory()
+	if err != nil {
+		return err
+	}
+	points := history[query]
+	if len(points) == 0 {
+		return fmt.Errorf("no inventory recorded for query '%s'", query)
+	}
+
+	headerColor.Printf("Inventory history for '%s':\n", query)
+	for i, point := range points {
+		change := ""
+		if i > 0 {
+			change = fmt.Sprintf(" (%+d)", point.Count-points[i-1].Count)
+		}
+		fmt.Printf("%s  %d%s\n", point.Time.Format("2006-01-02 15:04:05"), point.Count, change)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSaveInventory(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	history := make(map[string][]InventoryPoint)
+	recordInventory(history, "macbook", 12, 1)
+	if err := saveInventory(history); err != nil {
+		t.Fatal(err)
+	}
+	recordInventory(history, "macbook", 15, 1)
+	if err := saveInventory(history); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadInventory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points := loaded["macbook"]; len(points) != 2 || points[1].Count != 15 {
+		t.Errorf("loaded history = %+v, want counts 12 and 15", points)
+	}
+	// The temporary file is renamed over inventory.json, so nothing else is left behind
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != inventoryFile {
+		t.Errorf("directory holds %v, want only %s", entries, inventoryFile)
+	}
+}
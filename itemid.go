@@ -0,0 +1,97 @@
+/*
+Package main provides the extraction of eBay item ids from listing URLs.
+The id stays the same while tracking parameters in the URL change, so it
+keys the seen items and deduplication. Each marketplace domain has a list
+of patterns tried in order, the configured ones before the built-in ones.
+*/
+package main
+
+import (
+	"fmt"
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+defaultItemIDPatterns are the built-in patterns of every marketplace. Each captures
+the id in its first group.
+*/
+var defaultItemIDPatterns = []*regexp.Regexp{
+	// Current links: /itm/1234567890 and /itm/some-title/1234567890, on the mobile sites as well
+	regexp.MustCompile(`/itm/(?:[^/?#]+/)?(\d+)(?:[/?#]|$)`),
+	// Legacy links with the id in a query parameter, e.g.
+	// cgi.ebay.de/ws/eBayISAPI.dll?ViewItem&item=1234567890 or /vi/?itemId=1234567890
+	regexp.MustCompile(`[?&](?i:item|itemid)=(\d+)(?:[&#]|$)`),
+}
+
+// itemIDPatterns holds the configured patterns keyed by marketplace domain, swapped
+// as a whole on config reloads while the workers extract ids
+var itemIDPatterns atomic.Pointer[map[string][]*regexp.Regexp]
+
+// compileItemIDPatterns compiles configured patterns keyed by marketplace domain.
+// Every pattern needs a capture group for the id.
+func compileItemIDPatterns(config map[string][]string) (map[string][]*regexp.Regexp, error) {
+	compiled := make(map[string][]*regexp.Regexp, len(config))
+	for domain, patterns := range config {
+		key := marketplaceDomain(domain)
+		if _, ok := marketplaces[key]; !ok {
+			return nil, fmt.Errorf("item_id_patterns: unsupported marketplace %q", domain)
+		}
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("item_id_patterns for %s: %w", key, err)
+			}
+			if re.NumSubexp() < 1 {
+				return nil, fmt.Errorf("item_id_patterns for %s: %q has no group capturing the id", key, pattern)
+			}
+			compiled[key] = append(compiled[key], re)
+		}
+	}
+	return compiled, nil
+}
+
+// setItemIDPatterns replaces the configured patterns used by parseItemID
+func setItemIDPatterns(patterns map[string][]*regexp.Regexp) {
+	itemIDPatterns.Store(&patterns)
+}
+
+// marketplaceDomain normalizes a marketplace name to the key of the marketplaces map
+func marketplaceDomain(name string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "www.")
+}
+
+// listingDomain returns the marketplace domain a listing URL belongs to, also for
+// subdomains such as m.ebay.de and cgi.ebay.co.uk, or "" for other hosts
+func listingDomain(link string) string {
+	parsed, err := neturl.Parse(link)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for domain := range marketplaces {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return domain
+		}
+	}
+	return ""
+}
+
+// parseItemID extracts the numeric item id from a listing URL with the patterns of its
+// marketplace, the configured ones first. Falls back to the full URL when no id is found.
+func parseItemID(link string) string {
+	patterns := defaultItemIDPatterns
+	if configured := itemIDPatterns.Load(); configured != nil {
+		if custom := (*configured)[listingDomain(link)]; len(custom) > 0 {
+			patterns = append(custom[:len(custom):len(custom)], defaultItemIDPatterns...)
+		}
+	}
+	for _, re := range patterns {
+		if match := re.FindStringSubmatch(link); match != nil && match[1] != "" {
+			return match[1]
+		}
+	}
+	return link
+}
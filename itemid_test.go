@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestParseItemID(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"id path", "https://www.ebay.de/itm/1234567890", "1234567890"},
+		{"slug and id", "https://www.ebay.de/itm/nintendo-switch-oled/1234567890", "1234567890"},
+		{"query string", "https://www.ebay.de/itm/1234567890?hash=item1&epid=42&_trkparms=abc", "1234567890"},
+		{"fragment", "https://www.ebay.de/itm/1234567890#rpdCntId", "1234567890"},
+		{"slug, query and fragment", "https://www.ebay.de/itm/switch/1234567890?var=7#rpdCntId", "1234567890"},
+		{"no id", "https://www.ebay.de/sch/i.html?_nkw=switch", "https://www.ebay.de/sch/i.html?_nkw=switch"},
+		{"non-numeric id", "https://www.ebay.de/itm/switch", "https://www.ebay.de/itm/switch"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		if got := parseItemID(tt.url); got != tt.want {
+			t.Errorf("%s: parseItemID(%q) = %q, want %q", tt.name, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseItemIDMarketplaces(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.ebay.de/itm/145678901234", "145678901234"},
+		{"https://www.ebay.com/itm/145678901234?epid=123&hash=item21eb", "145678901234"},
+		{"https://www.ebay.co.uk/itm/Nintendo-Switch-OLED-White/145678901234", "145678901234"},
+		{"https://www.ebay.com.au/itm/nintendo-switch/145678901234?var=0#shpCntId", "145678901234"},
+		{"https://m.ebay.de/itm/145678901234", "145678901234"},
+		{"http://cgi.ebay.co.uk/ws/eBayISAPI.dll?ViewItem&item=145678901234", "145678901234"},
+		{"https://www.ebay.com/vi/?itemId=145678901234&_trksid=p2047675", "145678901234"},
+		// Titles starting with digits aren't taken for the id
+		{"https://www.ebay.de/itm/2024-switch-oled/145678901234", "145678901234"},
+		// The hash parameter isn't an item parameter
+		{"https://www.ebay.de/sch/i.html?hash=item21eb0c2c52", "https://www.ebay.de/sch/i.html?hash=item21eb0c2c52"},
+	}
+	for _, tt := range tests {
+		if got := parseItemID(tt.url); got != tt.want {
+			t.Errorf("parseItemID(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseItemIDConfiguredPatterns(t *testing.T) {
+	patterns, err := compileItemIDPatterns(map[string][]string{
+		"www.ebay.com": {`/p/\d+\?iid=(\d+)`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setItemIDPatterns(patterns)
+	t.Cleanup(func() { setItemIDPatterns(nil) })
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.ebay.com/p/19045386?iid=145678901234", "145678901234"},
+		// The built-in patterns still apply
+		{"https://www.ebay.com/itm/145678901234", "145678901234"},
+		// Patterns only apply to their marketplace
+		{"https://www.ebay.de/p/19045386?iid=145678901234", "https://www.ebay.de/p/19045386?iid=145678901234"},
+	}
+	for _, tt := range tests {
+		if got := parseItemID(tt.url); got != tt.want {
+			t.Errorf("parseItemID(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+
+	for name, config := range map[string]map[string][]string{
+		"unknown marketplace": {"ebay.xx": {`/itm/(\d+)`}},
+		"invalid pattern":     {"ebay.com": {`/itm/(\d+`}},
+		"no group":            {"ebay.com": {`/itm/\d+`}},
+	} {
+		if _, err := compileItemIDPatterns(config); err == nil {
+			t.Errorf("%s: compileItemIDPatterns accepted %v", name, config)
+		}
+	}
+}
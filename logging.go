@@ -0,0 +1,76 @@
+/*
+Package main provides the structured JSON log format.
+With log_format set to "json" the monitoring output is written as one JSON object
+per line so it can be shipped to a log collector.
+*/
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+)
+
+// jsonLogs switches the monitoring output to JSON log entries, set from the log_format option
+var jsonLogs bool
+
+// logSchemaVersion is the version of the JSON log entries, bumped only for breaking changes
+const logSchemaVersion = 1
+
+/*
+logEntry is a single line of JSON log output.
+NewItems is only set for search results, URL only for entries about a listing.
+*/
+type logEntry struct {
+	SchemaVersion int    `json:"schema_version"`
+	Timestamp     string `json:"timestamp"`
+	Level         string `json:"level"`
+	Query         string `json:"query,omitempty"`
+	NewItems      *int   `json:"new_items,omitempty"`
+	URL           string `json:"url,omitempty"`
+	Message       string `json:"message"`
+}
+
+// writeLogEntry writes an entry as a single line of JSON to stdout
+func writeLogEntry(entry logEntry) {
+	entry.SchemaVersion = logSchemaVersion
+	entry.Timestamp = time.Now().Format(time.RFC3339)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	os.Stdout.Write(append(data, '\n'))
+}
+
+// logInfo writes an informational entry, query and url may be empty
+func logInfo(query, url, message string) {
+	writeLogEntry(logEntry{Level: "info", Query: query, URL: url, Message: message})
+}
+
+// logError writes an error entry for a query
+func logError(query, message string) {
+	writeLogEntry(logEntry{Level: "error", Query: query, Message: message})
+}
+
+// logSearchResult writes the entry summarizing a finished search
+func logSearchResult(query string, newItems int) {
+	writeLogEntry(logEntry{Level: "info", Query: query, NewItems: &newItems, Message: "search finished"})
+}
+
+/*
+jsonLogWriter turns the lines of the standard logger into JSON log entries.
+Messages starting with "Error" are logged as errors, everything else as warnings.
+*/
+type jsonLogWriter struct{}
+
+// Write logs p as a single entry
+func (jsonLogWriter) Write(p []byte) (int, error) {
+	message := strings.TrimSpace(string(p))
+	level := "warning"
+	if strings.HasPrefix(message, "Error") {
+		level = "error"
+	}
+	writeLogEntry(logEntry{Level: level, Message: message})
+	return len(p), nil
+}
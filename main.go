@@ -6,16 +6,28 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 )
 
 // Color configurations for terminal output
@@ -27,6 +39,7 @@ var (
 	watcherColor = color.New(color.FgMagenta)
 	urlColor     = color.New(color.FgWhite, color.Underline)
 	headerColor  = color.New(color.FgHiWhite, color.Bold)
+	skipColor    = color.New(color.Faint)
 )
 
 /*
@@ -37,6 +50,16 @@ type SavedItem struct {
 	Item      Item      `json:"item"`
 	Found     time.Time `json:"found"`
 	QueryTerm string    `json:"query"`
+	// PreviousPrice is set when the entry records a price drop of a known item
+	PreviousPrice float64 `json:"previous_price,omitempty"`
+	// PreviousShipping is set when the entry records a shipping cost change of a known item,
+	// a pointer as the previous shipping may have been free
+	PreviousShipping *float64 `json:"previous_shipping,omitempty"`
+}
+
+// isNew reports whether the entry records a new item rather than a change of a known one
+func (s SavedItem) isNew() bool {
+	return s.PreviousPrice == 0 && s.PreviousShipping == nil
 }
 
 /*
@@ -45,120 +68,658 @@ Defines search criteria and monitoring behavior.
 */
 type SearchConfig struct {
 	Query       string      `json:"query"`
+	Marketplace string      `json:"marketplace,omitempty"`
+	MaxPages    int         `json:"max_pages,omitempty"`
+	SortOrder   SortOrder   `json:"sort_order,omitempty"`
 	ListingType ListingType `json:"listing_type"`
 	MinPrice    float64     `json:"min_price"`
 	MaxPrice    float64     `json:"max_price"`
 	MinWatchers int         `json:"min_watchers"`
 	MaxWatchers int         `json:"max_watchers"`
 	MaxTimeLeft *TimeRange  `json:"max_time_left"`
+
+	// CategoryID limits the search to an eBay category, 0 searches all categories
+	CategoryID int `json:"category_id,omitempty"`
+
+	// MinPageHitRatio stops pagination once less than this share of a page's listings match
+	MinPageHitRatio float64 `json:"min_page_hit_ratio,omitempty"`
+
+	// ConditionFilter is "new", "used", "refurbished" or "any"
+	ConditionFilter string `json:"condition,omitempty"`
+
+	// UseTotalPrice applies min_price/max_price to price plus shipping, UseMaxShipping
+	// adds the highest instead of the lowest cost of a shipping range
+	UseTotalPrice  bool `json:"use_total_price,omitempty"`
+	UseMaxShipping bool `json:"use_max_shipping,omitempty"`
+	// PriceRangeOverlap matches price ranges when any price in the range fits
+	PriceRangeOverlap bool `json:"price_range_overlap,omitempty"`
+
+	SubtitleContains    []string `json:"subtitle_contains,omitempty"`
+	SubtitleExcludes    []string `json:"subtitle_excludes,omitempty"`
+	ExactTokens         []string `json:"exact_tokens,omitempty"`
+	ExcludeKeywords     []string `json:"exclude_keywords,omitempty"`
+	RequireKeywords     []string `json:"require_keywords,omitempty"`
+	RequireVideo        bool     `json:"require_video,omitempty"`
+	TopRatedSellersOnly bool     `json:"top_rated_sellers_only,omitempty"`
+	EbayPlusOnly        bool     `json:"ebay_plus_only,omitempty"`
+	OnlyBestOffer       bool     `json:"only_best_offer,omitempty"`
+	ExcludeSponsored    bool     `json:"exclude_sponsored,omitempty"`
+	ExcludeBundles      bool     `json:"exclude_bundles,omitempty"`
+	OnlyBundles         bool     `json:"only_bundles,omitempty"`
+
+	MaxQuantityAvailable int `json:"max_quantity_available,omitempty"`
+	LowStockThreshold    int `json:"low_stock_threshold,omitempty"`
+	MinBids              int `json:"min_bids,omitempty"`
+	MinSellerFeedback    int `json:"min_seller_feedback,omitempty"`
+	MaxBids              int `json:"max_bids,omitempty"`
+
+	// MaxNormalizedPrice is the highest price per 100g, 100ml or piece
+	MaxNormalizedPrice float64 `json:"max_normalized_price,omitempty"`
+	// MaxPricePerUnit is the highest price per unit for titles like "100 Stück",
+	// SortByUnitPrice lists the matches by price per unit
+	MaxPricePerUnit float64 `json:"max_price_per_unit,omitempty"`
+	SortByUnitPrice bool    `json:"sort_by_unit_price,omitempty"`
+
+	// OnlyFreeReturns keeps items with free returns, StrictReturns also drops
+	// items that don't show a return policy
+	OnlyFreeReturns bool `json:"only_free_returns,omitempty"`
+	StrictReturns   bool `json:"strict_returns,omitempty"`
+
+	// LocationFilter keeps items whose location contains one of these phrases,
+	// AllowUnknownLocation also keeps items that don't show a location
+	LocationFilter       []string `json:"location_filter,omitempty"`
+	AllowUnknownLocation bool     `json:"allow_unknown_location,omitempty"`
+
+	// EndingSoonThreshold alerts once per auction when its time left drops to this
+	EndingSoonThreshold *TimeRange `json:"ending_soon_threshold,omitempty"`
+
+	// FilterLogic is "and" (default) or "or"
+	FilterLogic string `json:"filter_logic,omitempty"`
+
+	// CountOnly only checks eBay's total result count and runs a full scrape
+	// when it changes, or after FullScrapeEvery unchanged checks
+	CountOnly       bool `json:"count_only,omitempty"`
+	FullScrapeEvery int  `json:"full_scrape_every,omitempty"`
+
+	// CheckInterval overrides the global check interval for this search
+	CheckInterval int `json:"check_interval_seconds,omitempty"`
 }
 
 type Config struct {
 	CheckInterval int            `json:"check_interval_seconds"`
 	Searches      []SearchConfig `json:"searches"`
+
+	// StartupStaggerSeconds spreads the first cycle's searches over this window
+	StartupStaggerSeconds int `json:"startup_stagger_seconds,omitempty"`
+	// SearchJitterSeconds adds a random delay of up to this many seconds
+	// before each search on every cycle after the first
+	SearchJitterSeconds int `json:"search_jitter_seconds,omitempty"`
+	// PrettyFindings writes indented entries to findings.json
+	PrettyFindings bool `json:"pretty_findings,omitempty"`
+	// OutputFormat selects the findings files: "json" (default), "csv" or "both".
+	// findings.json is always written, "csv" and "both" add findings.csv.
+	OutputFormat string `json:"output_format,omitempty"`
+	// ActiveWindows limits scraping to these weekday/time ranges
+	ActiveWindows []ActiveWindow `json:"active_windows,omitempty"`
+	// HTTPAddr enables the HTTP server with /findings and /healthz, e.g. ":8080"
+	HTTPAddr string `json:"http_addr,omitempty"`
+	// Metrics serves Prometheus metrics on /metrics of the HTTP server
+	Metrics bool `json:"metrics,omitempty"`
+	// HeartbeatURL is pinged after every cycle without scrape errors
+	HeartbeatURL string `json:"heartbeat_url,omitempty"`
+	// WebhookURL receives a JSON POST for every new item
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// Templates replaces the built-in notification templates, keyed by channel
+	// ("telegram", "email", "email_subject" or "webhook"), with Go templates over SavedItem
+	Templates map[string]string `json:"templates,omitempty"`
+	// WebhookSchemaVersion pins the webhook payload to an older schema version, 0 for the latest
+	WebhookSchemaVersion int `json:"webhook_schema_version,omitempty"`
+	// Email sends an email for every new item through SMTP
+	Email *EmailConfig `json:"email,omitempty"`
+	// Telegram sends new items to a Telegram chat through a bot
+	Telegram *TelegramConfig `json:"telegram,omitempty"`
+	// UseConditionalRequests revalidates pages with ETag/Last-Modified instead of refetching
+	UseConditionalRequests bool `json:"use_conditional_requests,omitempty"`
+	// InventoryChangeThreshold is how much a query's match count must move to be recorded
+	InventoryChangeThreshold int `json:"inventory_change_threshold,omitempty"`
+	// TLS customizes certificate verification for all outgoing requests
+	TLS *TLSConfig `json:"tls,omitempty"`
+	// ProxyURL routes all requests through a proxy, ProxyURLs rotates through
+	// several proxies per request. Credentials go in the URL.
+	ProxyURL  string   `json:"proxy_url,omitempty"`
+	ProxyURLs []string `json:"proxy_urls,omitempty"`
+	// CookieFile is a Netscape cookie file whose cookies, e.g. a logged-in eBay
+	// session, are sent with every request
+	CookieFile string `json:"cookie_file,omitempty"`
+	// TrackTitleChanges reports when a previously seen listing's title is edited
+	TrackTitleChanges bool `json:"track_title_changes,omitempty"`
+	// TrackShippingChanges records when the shipping cost of a previously seen listing changes
+	TrackShippingChanges bool `json:"track_shipping_changes,omitempty"`
+	// NotifyShippingChanges also sends the recorded shipping changes to the notifiers
+	NotifyShippingChanges bool `json:"notify_shipping_changes,omitempty"`
+	// TraceTimings logs a DNS/connect/TLS/server timing breakdown for every request
+	TraceTimings bool `json:"trace_timings,omitempty"`
+	// UserAgent overrides the browser user agent sent to eBay
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// TimeoutSeconds limits each HTTP request, MaxRetries and RetryBackoffSeconds
+	// control how transient failures are retried. BackoffStrategy is "constant",
+	// "linear", "exponential" (default) or "exponential_jitter", starting at
+	// RetryBackoffSeconds and growing to at most MaxBackoffSeconds (0 for no limit).
+	TimeoutSeconds      int    `json:"timeout_seconds"`
+	MaxRetries          int    `json:"max_retries"`
+	RetryBackoffSeconds int    `json:"retry_backoff_seconds"`
+	BackoffStrategy     string `json:"backoff_strategy,omitempty"`
+	MaxBackoffSeconds   int    `json:"max_backoff_seconds,omitempty"`
+	// MaxConcurrency is how many searches are scraped at the same time
+	MaxConcurrency int `json:"max_concurrency"`
+	// AdaptiveConcurrency lowers the concurrency on 429 and 403 responses and raises it
+	// after sustained success, starting at InitialConcurrency (MaxConcurrency when 0)
+	// and staying between MinConcurrency (1 when 0) and MaxConcurrency
+	AdaptiveConcurrency bool `json:"adaptive_concurrency,omitempty"`
+	MinConcurrency      int  `json:"min_concurrency,omitempty"`
+	InitialConcurrency  int  `json:"initial_concurrency,omitempty"`
+	// MaxRequestsPerMinute caps the requests to eBay across all searches, 0 for no limit
+	MaxRequestsPerMinute int `json:"max_requests_per_minute,omitempty"`
+	// DryRun prints matches without writing findings, logs or other state, set by -dry-run
+	DryRun bool `json:"-"`
+	// LogFormat is "text" (default) for colored terminal output or "json" for one JSON object per line
+	LogFormat string `json:"log_format,omitempty"`
+	// SeenRehydrateDays rebuilds the seen items on startup from the daily logs of this many days
+	SeenRehydrateDays int `json:"seen_rehydrate_days,omitempty"`
+	// LogCompressAfterDays gzips daily logs older than this many days, LogRetentionDays
+	// deletes daily logs and summaries older than this many days; 0 disables either
+	LogCompressAfterDays int `json:"log_compress_after_days,omitempty"`
+	LogRetentionDays     int `json:"log_retention_days,omitempty"`
+	// BaseCurrency converts prices to this currency before filtering, ExchangeRates
+	// overrides the built-in rates with the value of one unit in euros
+	BaseCurrency  string             `json:"base_currency,omitempty"`
+	ExchangeRates map[string]float64 `json:"exchange_rates,omitempty"`
+	// DedupTTLDays forgets seen items that haven't shown up for this many days, 0 remembers them forever
+	DedupTTLDays int `json:"dedup_ttl_days,omitempty"`
+	// SimilarityThreshold skips new items whose title and price are this similar, from 0 to 1,
+	// to an item already seen in this session; 0 disables fuzzy duplicate detection
+	SimilarityThreshold float64 `json:"similarity_threshold,omitempty"`
+	// Selectors override the CSS selectors used to read result pages, e.g. after an eBay HTML change
+	Selectors *Selectors `json:"selectors,omitempty"`
+	// SeparateFiles also writes each query's findings to findings/<query>.json
+	SeparateFiles bool `json:"separate_files,omitempty"`
+	// ItemIDPatterns adds patterns extracting item ids from listing URLs, keyed by
+	// marketplace domain and tried before the built-in ones
+	ItemIDPatterns map[string][]string `json:"item_id_patterns,omitempty"`
+	// Storage selects the backend the findings are saved to, "json" (default) for findings.json
+	Storage string `json:"storage,omitempty"`
 }
 
-// loadConfig reads and parses the configuration file
-func loadConfig() (*Config, error) {
-	// Try to load config.json
-	data, err := os.ReadFile("config.json")
-	if err != nil {
-		// If config doesn't exist, try to copy template
+// defaultConfig returns a configuration with default settings and no searches
+func defaultConfig() Config {
+	return Config{
+		CheckInterval:       300,
+		TimeoutSeconds:      30,
+		MaxRetries:          2,
+		RetryBackoffSeconds: 2,
+		MaxConcurrency:      4,
+	}
+}
+
+// Validate checks the configuration for contradictory or unsupported settings
+// and returns an error listing every problem found
+func (c *Config) Validate() error {
+	var problems []error
+	if c.CheckInterval <= 0 {
+		problems = append(problems, fmt.Errorf("check_interval_seconds must be positive, got %d", c.CheckInterval))
+	}
+	if c.MaxConcurrency < 1 {
+		problems = append(problems, fmt.Errorf("max_concurrency must be at least 1, got %d", c.MaxConcurrency))
+	}
+	if c.MinConcurrency < 0 || c.MinConcurrency > c.MaxConcurrency {
+		problems = append(problems, fmt.Errorf("min_concurrency must be between 0 and max_concurrency, got %d", c.MinConcurrency))
+	}
+	if c.InitialConcurrency < 0 || c.InitialConcurrency > c.MaxConcurrency ||
+		(c.InitialConcurrency > 0 && c.InitialConcurrency < c.MinConcurrency) {
+		problems = append(problems, fmt.Errorf("initial_concurrency must be between min_concurrency and max_concurrency, got %d", c.InitialConcurrency))
+	}
+	if c.LogCompressAfterDays < 0 || c.LogRetentionDays < 0 {
+		problems = append(problems, errors.New("log_compress_after_days and log_retention_days must not be negative"))
+	}
+	if c.BaseCurrency != "" {
+		if _, err := newStaticRates(c.ExchangeRates).Rate(strings.ToUpper(c.BaseCurrency), "EUR"); err != nil {
+			problems = append(problems, fmt.Errorf("base_currency: %w, add it to exchange_rates", err))
+		}
+	}
+	if c.DedupTTLDays < 0 {
+		problems = append(problems, fmt.Errorf("dedup_ttl_days must not be negative, got %d", c.DedupTTLDays))
+	}
+	if c.SimilarityThreshold < 0 || c.SimilarityThreshold > 1 {
+		problems = append(problems, fmt.Errorf("similarity_threshold must be between 0 and 1, got %g", c.SimilarityThreshold))
+	}
+	if _, err := c.backoffStrategy(); err != nil {
+		problems = append(problems, err)
+	}
+	if c.RetryBackoffSeconds < 0 || c.MaxBackoffSeconds < 0 {
+		problems = append(problems, errors.New("retry_backoff_seconds and max_backoff_seconds must not be negative"))
+	}
+	if c.MaxRequestsPerMinute < 0 {
+		problems = append(problems, fmt.Errorf("max_requests_per_minute must not be negative, got %d", c.MaxRequestsPerMinute))
+	}
+	if len(c.Searches) == 0 {
+		problems = append(problems, errors.New("no searches configured"))
+	}
+
+	for i, search := range c.Searches {
+		name := fmt.Sprintf("search %d (%q)", i+1, search.Query)
+		if strings.TrimSpace(search.Query) == "" {
+			problems = append(problems, fmt.Errorf("search %d: query is empty", i+1))
+		}
+		if search.CheckInterval < 0 {
+			problems = append(problems, fmt.Errorf("%s: check_interval_seconds must not be negative, got %d", name, search.CheckInterval))
+		}
+		if search.MinPrice >= 0 && search.MaxPrice >= 0 && search.MinPrice > search.MaxPrice {
+			problems = append(problems, fmt.Errorf("%s: min_price %.2f is above max_price %.2f", name, search.MinPrice, search.MaxPrice))
+		}
+		if search.MinWatchers > 0 && search.MaxWatchers > 0 && search.MinWatchers > search.MaxWatchers {
+			problems = append(problems, fmt.Errorf("%s: min_watchers %d is above max_watchers %d", name, search.MinWatchers, search.MaxWatchers))
+		}
+		if search.MinBids > 0 && search.MaxBids > 0 && search.MinBids > search.MaxBids {
+			problems = append(problems, fmt.Errorf("%s: min_bids %d is above max_bids %d", name, search.MinBids, search.MaxBids))
+		}
+		if search.OnlyBestOffer && search.ListingType == Auction {
+			problems = append(problems, fmt.Errorf("%s: only_best_offer can't match auctions, use a buy now or all listing type", name))
+		}
+		if _, err := lookupMarketplace(search.Marketplace); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", name, err))
+		}
+		if search.MinPageHitRatio < 0 || search.MinPageHitRatio > 1 {
+			problems = append(problems, fmt.Errorf("%s: min_page_hit_ratio must be between 0 and 1, got %g", name, search.MinPageHitRatio))
+		}
+		if search.CategoryID < 0 {
+			problems = append(problems, fmt.Errorf("%s: category_id must not be negative, got %d", name, search.CategoryID))
+		}
+		if _, err := sortParam(search.SortOrder); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", name, err))
+		}
+		if !validConditionFilter(search.ConditionFilter) {
+			problems = append(problems, fmt.Errorf("%s: unknown condition %q", name, search.ConditionFilter))
+		}
+		switch strings.ToLower(search.FilterLogic) {
+		case "", "and", "or":
+		default:
+			problems = append(problems, fmt.Errorf("%s: filter_logic %q, expected \"and\" or \"or\"", name, search.FilterLogic))
+		}
+	}
+
+	for _, window := range c.ActiveWindows {
+		if err := window.validate(); err != nil {
+			problems = append(problems, fmt.Errorf("active window: %w", err))
+		}
+	}
+
+	switch c.LogFormat {
+	case "", "text", "json":
+	default:
+		problems = append(problems, fmt.Errorf("log_format %q, expected \"text\" or \"json\"", c.LogFormat))
+	}
+
+	if _, err := newStore(c); err != nil {
+		problems = append(problems, err)
+	}
+	if _, err := webhookPayload(SavedItem{}, c.WebhookSchemaVersion); err != nil {
+		problems = append(problems, err)
+	}
+	if _, err := parseTemplates(c.Templates); err != nil {
+		problems = append(problems, err)
+	}
+	if _, err := compileItemIDPatterns(c.ItemIDPatterns); err != nil {
+		problems = append(problems, err)
+	}
+
+	switch c.OutputFormat {
+	case "", "json", "csv", "both":
+	default:
+		problems = append(problems, fmt.Errorf("output_format %q, expected \"json\", \"csv\" or \"both\"", c.OutputFormat))
+	}
+
+	for i, raw := range c.proxyURLs() {
+		if _, err := parseProxyURL(raw); err != nil {
+			problems = append(problems, fmt.Errorf("proxy %d: %w", i+1, err))
+		}
+	}
+
+	if c.Email != nil {
+		if err := c.Email.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.Telegram != nil {
+		if err := c.Telegram.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.Selectors != nil {
+		if err := c.Selectors.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if c.Metrics && c.HTTPAddr == "" {
+		problems = append(problems, errors.New("metrics needs http_addr to be set"))
+	}
+	return errors.Join(problems...)
+}
+
+// loadConfig reads and parses the configuration file at path. With useTemplate a missing
+// file is replaced by config.template.json, which is copied to path if saveTemplate is set.
+func loadConfig(path string, useTemplate, saveTemplate bool) (*Config, error) {
+	// Try to load the config file
+	data, err := os.ReadFile(path)
+	if err != nil && useTemplate {
+		// If config doesn't exist, try the template
 		templateData, templateErr := os.ReadFile("config.template.json")
-		if templateErr == nil {
-			if copyErr := os.WriteFile("config.json", templateData, 0644); copyErr == nil {
+		if templateErr == nil && saveTemplate {
+			if copyErr := os.WriteFile(path, templateData, 0644); copyErr == nil {
 				data = templateData
 			}
-		}
-		if data == nil {
-			return nil, err
+		} else if templateErr == nil {
+			data = templateData
 		}
 	}
+	if data == nil {
+		return nil, err
+	}
 
-	var config Config
+	config := defaultConfig()
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
 	return &config, nil
 }
 
-// getDailyLogFile returns a file handle for today's log file
-func getDailyLogFile() (*os.File, error) {
-	today := time.Now().Format("2006-01-02")
-	logDir := "logs"
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return nil, err
+// dailyLogPath returns the path of the daily log file for the given day
+func dailyLogPath(day time.Time) string {
+	return filepath.Join("logs", fmt.Sprintf("findings_%s.json", day.Format("2006-01-02")))
+}
+
+// rotateLogs compresses and deletes old daily logs as configured, reporting what was done
+func rotateLogs(config *Config) {
+	if config.LogCompressAfterDays <= 0 && config.LogRetentionDays <= 0 {
+		return
+	}
+	compressed, deleted, err := rotateDailyLogs(config.LogCompressAfterDays, config.LogRetentionDays, time.Now())
+	if err != nil {
+		log.Printf("Error rotating daily logs: %v", err)
+	}
+	if compressed == 0 && deleted == 0 {
+		return
+	}
+	message := fmt.Sprintf("Compressed %d and deleted %d old log files", compressed, deleted)
+	if jsonLogs {
+		logInfo("", "", message)
+	} else {
+		headerColor.Println(message)
 	}
-	return os.OpenFile(
-		filepath.Join(logDir, fmt.Sprintf("findings_%s.json", today)),
-		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
-		0644,
-	)
 }
 
 // printItem displays a single item in the terminal with color formatting
 func printItem(item Item, query string) {
-	fmt.Printf("\n%s\n", strings.Repeat("-", 80))
+	fmt.Fprintf(color.Output, "\n%s\n", strings.Repeat("-", 80))
 	titleColor.Printf("Title: %s\n", item.Title)
+	if item.Subtitle != "" {
+		fmt.Fprintf(color.Output, "Subtitle: %s\n", item.Subtitle)
+	}
 	priceColor.Printf("Price: %s\n", item.Price)
+	if item.Condition != "" {
+		fmt.Fprintf(color.Output, "Condition: %s\n", item.Condition)
+	}
+	if item.SellerName != "" {
+		fmt.Fprintf(color.Output, "Seller: %s (%d, %.1f%%)\n", item.SellerName, item.SellerFeedback, item.SellerRating)
+	}
+	if item.AcceptsOffers {
+		fmt.Fprintln(color.Output, "Accepts offers")
+	}
+	if item.ShippingText != "" {
+		fmt.Fprintf(color.Output, "Shipping: %s\n", item.ShippingText)
+	}
+	if item.NormalizedPrice > 0 {
+		perUnit := "100" + item.PackUnit
+		if item.PackUnit == "pcs" {
+			perUnit = "piece"
+		}
+		priceColor.Printf("Unit price: %.2f per %s\n", item.NormalizedPrice, perUnit)
+	}
 
 	listingType := buyNowColor.Sprint("Buy Now")
 	if item.IsAuction {
 		listingType = auctionColor.Sprintf("Auction - %s remaining", item.TimeLeft)
 	}
 
-	fmt.Printf("Type: %s", listingType)
+	fmt.Fprintf(color.Output, "Type: %s", listingType)
+	if item.Bids > 0 {
+		watcherColor.Printf(" (%d bids)", item.Bids)
+	}
 	if item.Watchers > 0 {
 		watcherColor.Printf(" (%d watchers)", item.Watchers)
 	}
-	fmt.Println()
+	fmt.Fprintln(color.Output)
 
 	urlColor.Printf("URL: %s\n", item.URL)
 	headerColor.Printf("Query: %s\n", query)
 }
 
-// saveNewItems persists newly found items to both daily log and findings.json
-func saveNewItems(items []Item, query string, seenItems map[string]bool) {
-	// Save to findings.json
-	file, err := os.OpenFile("findings.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Printf("Error opening findings.json: %v", err)
+// saveNewItems persists newly found items and price drops of known items to the daily log,
+// the store and findings.csv with the csv output format, and returns how many of the items
+// had not been seen before along with the saved entries, which the caller passes on to the
+// notifiers. seenItems maps each known item id to its last seen price and time,
+// titles the ids of the items seen in this session to their titles.
+func saveNewItems(config *Config, store Store, items []Item, query string, seenItems map[string]seenItem, titles map[string]string) (int, []SavedItem) {
+	// In a dry run items are only printed, still deduplicated within the session
+	now := time.Now()
+	newItems := 0
+	var saved []SavedItem
+
+	for _, item := range items {
+		last, seen := seenItems[item.ID]
+		lastPrice := last.Price
+		seenItems[item.ID] = seenItem{Price: item.PriceValue, Shipping: item.ShippingCost, LastSeen: now}
+		titles[item.ID] = item.Title
+		// Unknown shipping costs are -1 and never count as a change
+		shippingChanged := seen && config.TrackShippingChanges && last.Shipping >= 0 && item.ShippingCost >= 0 &&
+			toCents(item.ShippingCost) != toCents(last.Shipping)
+		if seen && item.PriceCents >= toCents(lastPrice) && !shippingChanged {
+			continue
+		}
+		if !seen && config.SimilarityThreshold > 0 {
+			if similarID, ok := findSimilarItem(item, seenItems, titles, config.SimilarityThreshold); ok {
+				message := fmt.Sprintf("Skipping %s, similar to %s", item.Title, titles[similarID])
+				if jsonLogs {
+					logInfo(query, item.URL, message)
+				} else {
+					skipColor.Printf("[%s] %s\n", query, message)
+				}
+				continue
+			}
+		}
+
+		savedItem := SavedItem{
+			Item:      item,
+			Found:     now,
+			QueryTerm: query,
+		}
+		priceDropped := seen && item.PriceCents < toCents(lastPrice)
+		if priceDropped {
+			savedItem.PreviousPrice = lastPrice
+		}
+		if shippingChanged {
+			previousShipping := last.Shipping
+			savedItem.PreviousShipping = &previousShipping
+		}
+		if !seen {
+			newItems++
+		}
+		saved = append(saved, savedItem)
+
+		// Print to terminal
+		switch {
+		case jsonLogs && !seen:
+			logInfo(query, item.URL, fmt.Sprintf("New item: %s (%s)", item.Title, item.Price))
+		case jsonLogs:
+			if priceDropped {
+				logInfo(query, item.URL, fmt.Sprintf("Price drop: %s (%.2f -> %.2f)", item.Title, lastPrice, item.PriceValue))
+			}
+			if shippingChanged {
+				logInfo(query, item.URL, fmt.Sprintf("Shipping changed: %s (%.2f -> %.2f)", item.Title, last.Shipping, item.ShippingCost))
+			}
+		default:
+			printItem(item, query)
+			if priceDropped {
+				auctionColor.Printf("Price drop: %.2f -> %.2f\n", lastPrice, item.PriceValue)
+			}
+			if shippingChanged {
+				auctionColor.Printf("Shipping changed: %.2f -> %.2f\n", last.Shipping, item.ShippingCost)
+			}
+		}
+	}
+
+	if !config.DryRun && len(saved) > 0 {
+		if err := appendDailyLog(saved); err != nil {
+			log.Printf("Error saving to daily log: %v", err)
+		}
+	}
+	// The store is written for every output format, as the seen items, the server,
+	// -diff and -import-findings rely on it. The JSON store rewrites findings.json
+	// once per search to keep it a valid JSON array.
+	if !config.DryRun && len(saved) > 0 {
+		if err := saveAll(store, saved); err != nil {
+			log.Printf("Error saving findings: %v", err)
+		}
+	}
+	if !config.DryRun && len(saved) > 0 && (config.OutputFormat == "csv" || config.OutputFormat == "both") {
+		if err := saveCSV(saved, "findings.csv"); err != nil {
+			log.Printf("Error saving to findings.csv: %v", err)
+		}
+	}
+	return newItems, saved
+}
+
+// alertLowStock prints a one-time alert for items whose remaining quantity dropped to the threshold
+func alertLowStock(items []Item, query string, threshold int, alerted map[string]bool) {
+	if threshold <= 0 {
 		return
 	}
-	defer file.Close()
+	for _, item := range items {
+		if item.QuantityAvailable == 0 || item.QuantityAvailable > threshold || alerted[item.ID] {
+			continue
+		}
+		alerted[item.ID] = true
+		if jsonLogs {
+			logInfo(query, item.URL, fmt.Sprintf("Low stock: only %d left of %s", item.QuantityAvailable, item.Title))
+			continue
+		}
+		auctionColor.Printf("Low stock for '%s': only %d left of %s\n", query, item.QuantityAvailable, item.Title)
+		urlColor.Printf("URL: %s\n", item.URL)
+	}
+}
 
-	// Get daily log file
-	dailyLog, err := getDailyLogFile()
+// alertEndingSoon prints a one-time alert for auctions whose time left dropped to the
+// search's ending soon threshold
+func alertEndingSoon(items []Item, search SearchConfig, alerted map[string]bool) {
+	if search.EndingSoonThreshold == nil {
+		return
+	}
+	marketplace, err := lookupMarketplace(search.Marketplace)
 	if err != nil {
-		log.Printf("Error opening daily log: %v", err)
 		return
 	}
-	defer dailyLog.Close()
-
-	encoder := json.NewEncoder(file)
-	dailyEncoder := json.NewEncoder(dailyLog)
-	now := time.Now()
+	threshold := search.EndingSoonThreshold.toMinutes()
+	for _, item := range items {
+		if !item.IsAuction || alerted[item.ID] {
+			continue
+		}
+		timeLeft := parseTimeLeft(item.TimeLeft, marketplace.German)
+		if timeLeft == nil || timeLeft.toMinutes() > threshold {
+			continue
+		}
+		alerted[item.ID] = true
+		if jsonLogs {
+			logInfo(search.Query, item.URL, fmt.Sprintf("Ending soon: %s (%s, %s)", item.Title, item.Price, strings.TrimSpace(item.TimeLeft)))
+			continue
+		}
+		auctionColor.Printf("Ending soon for '%s': %s (%s, %s)\n", search.Query, item.Title, item.Price, strings.TrimSpace(item.TimeLeft))
+		urlColor.Printf("URL: %s\n", item.URL)
+	}
+}
 
+// reportTitleChanges prints listings whose title differs from the last time they were seen
+// and remembers the current titles for the next cycle
+func reportTitleChanges(items []Item, query string, titles map[string]string) {
 	for _, item := range items {
-		if !seenItems[item.URL] {
-			seenItems[item.URL] = true
-			savedItem := SavedItem{
-				Item:      item,
-				Found:     now,
-				QueryTerm: query,
-			}
+		previous, ok := titles[item.ID]
+		titles[item.ID] = item.Title
+		if !ok || previous == item.Title {
+			continue
+		}
+		if jsonLogs {
+			logInfo(query, item.URL, fmt.Sprintf("Title changed from %q to %q", previous, item.Title))
+			continue
+		}
+		auctionColor.Printf("Title changed for '%s':\n  old: %s\n  new: %s\n", query, previous, item.Title)
+		urlColor.Printf("URL: %s\n", item.URL)
+	}
+}
 
-			// Save to both files
-			if err := encoder.Encode(savedItem); err != nil {
-				log.Printf("Error saving to findings.json: %v", err)
-			}
-			if err := dailyEncoder.Encode(savedItem); err != nil {
-				log.Printf("Error saving to daily log: %v", err)
-			}
+// countState tracks the light result-count checks of a count-only search
+type countState struct {
+	lastCount   int
+	checksSince int
+}
 
-			// Print to terminal
-			printItem(item, query)
-		}
+// needsFullScrape checks the result count of a count-only search and reports whether
+// a full scrape should follow. Changes in the count are printed as they are seen.
+func needsFullScrape(ctx context.Context, scraper *Scraper, search SearchConfig, state *countState) (bool, error) {
+	count, err := scraper.ScrapeResultCountContext(ctx, search.Query)
+	if err != nil {
+		return false, err
+	}
+
+	changed := state.lastCount >= 0 && count != state.lastCount
+	if changed && jsonLogs {
+		logInfo(search.Query, "", fmt.Sprintf("Result count changed from %d to %d", state.lastCount, count))
+	} else if changed {
+		headerColor.Printf("Query '%s': Result count changed from %d to %d\n",
+			search.Query,
+			state.lastCount,
+			count)
+	}
+	first := state.lastCount < 0
+	state.lastCount = count
+	state.checksSince++
+
+	if first || changed || (search.FullScrapeEvery > 0 && state.checksSince >= search.FullScrapeEvery) {
+		state.checksSince = 0
+		return true, nil
+	}
+	return false, nil
+}
+
+// sendHeartbeat pings the configured heartbeat URL with a short cycle summary as the body
+func sendHeartbeat(client *http.Client, url string, summary string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(summary))
+	if err != nil {
+		log.Printf("Error sending heartbeat: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Error sending heartbeat: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("Heartbeat returned status %s", resp.Status)
 	}
 }
 
@@ -255,21 +816,8 @@ func getMaxTimeRemaining() *TimeRange {
 			return nil
 		}
 
-		parts := strings.Split(input, ":")
-		if len(parts) == 3 {
-			days, errD := strconv.Atoi(parts[0])
-			hours, errH := strconv.Atoi(parts[1])
-			minutes, errM := strconv.Atoi(parts[2])
-
-			if errD == nil && errH == nil && errM == nil &&
-				days >= 0 && hours >= 0 && hours < 24 &&
-				minutes >= 0 && minutes < 60 {
-				return &TimeRange{
-					Days:    days,
-					Hours:   hours,
-					Minutes: minutes,
-				}
-			}
+		if timeRange, err := parseTimeRange(input); err == nil {
+			return timeRange
 		}
 		fmt.Println("Please enter time in format DD:HH:MM or press enter for no limit")
 	}
@@ -293,29 +841,338 @@ func promptForSearch() SearchConfig {
 	}
 }
 
-// saveConfig writes the current configuration to config.json
-func saveConfig(config *Config) error {
+// saveConfig writes the current configuration to the config file at path
+func saveConfig(config *Config, path string) error {
 	data, err := json.MarshalIndent(config, "", "    ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile("config.json", data, 0644)
+	return os.WriteFile(path, data, 0644)
+}
+
+// searchOffset returns how long after the start of a round the search at index i starts.
+// On the first cycle searches are spread evenly over the startup stagger window,
+// afterwards each search starts after a random jitter if configured. Offsets count
+// from the round start rather than from the previous search, so they don't add up.
+func searchOffset(config *Config, i int, firstCycle bool) time.Duration {
+	if firstCycle {
+		if config.StartupStaggerSeconds <= 0 {
+			return 0
+		}
+		window := time.Duration(config.StartupStaggerSeconds) * time.Second
+		return window * time.Duration(i) / time.Duration(len(config.Searches))
+	}
+	if config.SearchJitterSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(config.SearchJitterSeconds) * int64(time.Second)))
+}
+
+/*
+searchState is what the monitor remembers about a single search between rounds.
+nextRun is zero until the search has run for the first time.
+*/
+type searchState struct {
+	count       *countState
+	cache       *ResponseCache
+	exactTokens []*regexp.Regexp // The search's compiled exact tokens, shared by its rounds
+	nextRun     time.Time
+}
+
+// searchStates returns the state of each search in config, reusing the states
+// of unchanged searches from the previous configuration
+func searchStates(config *Config, previous []SearchConfig, states []*searchState) []*searchState {
+	newStates := make([]*searchState, len(config.Searches))
+	used := make([]bool, len(previous))
+	for i, search := range config.Searches {
+		for j, old := range previous {
+			if !used[j] && reflect.DeepEqual(search, old) {
+				used[j] = true
+				newStates[i] = states[j]
+				break
+			}
+		}
+		if newStates[i] == nil {
+			newStates[i] = &searchState{
+				count:       &countState{lastCount: -1},
+				exactTokens: compileExactTokens(search.ExactTokens),
+			}
+		}
+		// One cache per search, since cached results are already filtered
+		if !config.UseConditionalRequests {
+			newStates[i].cache = nil
+		} else if newStates[i].cache == nil {
+			newStates[i].cache = NewResponseCache()
+		}
+	}
+	return newStates
+}
+
+// searchInterval returns how often a search runs, its own interval or the global one
+func searchInterval(config *Config, search SearchConfig) time.Duration {
+	if search.CheckInterval > 0 {
+		return time.Duration(search.CheckInterval) * time.Second
+	}
+	return time.Duration(config.CheckInterval) * time.Second
+}
+
+// nextDue returns the earliest time one of the searches is due again
+func nextDue(states []*searchState) time.Time {
+	var next time.Time
+	for _, state := range states {
+		if next.IsZero() || state.nextRun.Before(next) {
+			next = state.nextRun
+		}
+	}
+	return next
+}
+
+// fileModTime returns the modification time of a file, reporting false if it can't be read
+func fileModTime(path string) (time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// reloadConfig re-reads the config file when its modification time differs from lastMod.
+// It returns nil if the file is unchanged, missing or invalid, in which case the
+// previous configuration should be kept.
+func reloadConfig(path string, lastMod *time.Time) *Config {
+	modTime, ok := fileModTime(path)
+	if !ok || modTime.Equal(*lastMod) {
+		return nil
+	}
+	*lastMod = modTime
+
+	config, err := loadConfig(path, false, false)
+	if err == nil {
+		err = config.Validate()
+	}
+	if err != nil {
+		log.Printf("Keeping the previous configuration, %s is invalid:\n%v", path, err)
+		return nil
+	}
+	return config
+}
+
+// clientSettingsChanged reports whether the settings the HTTP client is built from differ
+func clientSettingsChanged(old, new *Config) bool {
+	return !reflect.DeepEqual(old.proxyURLs(), new.proxyURLs()) ||
+		!reflect.DeepEqual(old.TLS, new.TLS) ||
+		old.CookieFile != new.CookieFile ||
+		old.TimeoutSeconds != new.TimeoutSeconds
+}
+
+// concurrencySettingsChanged reports whether the adaptive concurrency settings differ,
+// otherwise the controller keeps the concurrency it adapted to
+func concurrencySettingsChanged(old, new *Config) bool {
+	return old.AdaptiveConcurrency != new.AdaptiveConcurrency ||
+		old.MinConcurrency != new.MinConcurrency ||
+		old.InitialConcurrency != new.InitialConcurrency ||
+		old.MaxConcurrency != new.MaxConcurrency
+}
+
+// restartOnlyChanges lists the changed keys that are only applied at startup
+func restartOnlyChanges(old, new *Config) []string {
+	var keys []string
+	if old.HTTPAddr != new.HTTPAddr {
+		keys = append(keys, "http_addr")
+	}
+	if old.Metrics != new.Metrics {
+		keys = append(keys, "metrics")
+	}
+	if old.LogFormat != new.LogFormat {
+		keys = append(keys, "log_format")
+	}
+	return keys
+}
+
+// backoffStrategy returns the configured retry backoff strategy
+func (c *Config) backoffStrategy() (BackoffStrategy, error) {
+	return newBackoffStrategy(c.BackoffStrategy,
+		time.Duration(c.RetryBackoffSeconds)*time.Second,
+		time.Duration(c.MaxBackoffSeconds)*time.Second)
+}
+
+// concurrencyController creates the adaptive concurrency controller, nil unless adaptive_concurrency is set
+func (c *Config) concurrencyController(metrics *Metrics) *ConcurrencyController {
+	if !c.AdaptiveConcurrency {
+		return nil
+	}
+	min, start := c.MinConcurrency, c.InitialConcurrency
+	if min == 0 {
+		min = 1
+	}
+	if start == 0 {
+		start = c.MaxConcurrency
+	}
+	return NewConcurrencyController(start, min, c.MaxConcurrency, metrics)
+}
+
+// newSearchScraper creates a scraper applying the filters of a search and the global request settings
+func newSearchScraper(config *Config, search SearchConfig, state *searchState, client *http.Client, limiter *RateLimiter, metrics *Metrics, concurrency *ConcurrencyController) *Scraper {
+	scraper := NewScraper()
+	scraper.Marketplace = search.Marketplace
+	if search.MaxPages > 0 {
+		scraper.MaxPages = search.MaxPages
+	}
+	scraper.MinPageHitRatio = search.MinPageHitRatio
+	scraper.SortOrder = search.SortOrder
+	scraper.CategoryID = search.CategoryID
+	scraper.ListingType = search.ListingType
+	scraper.MinPrice = search.MinPrice
+	scraper.MaxPrice = search.MaxPrice
+	scraper.UseTotalPrice = search.UseTotalPrice
+	scraper.UseMaxShipping = search.UseMaxShipping
+	scraper.PriceRangeOverlap = search.PriceRangeOverlap
+	scraper.ConditionFilter = search.ConditionFilter
+	scraper.MaxTimeLeft = search.MaxTimeLeft
+	scraper.MinWatchers = search.MinWatchers
+	scraper.MaxWatchers = search.MaxWatchers
+	scraper.MinBids = search.MinBids
+	scraper.MaxBids = search.MaxBids
+	scraper.FilterLogic = search.FilterLogic
+	scraper.SubtitleContains = search.SubtitleContains
+	scraper.SubtitleExcludes = search.SubtitleExcludes
+	scraper.ExactTokens = search.ExactTokens
+	scraper.exactTokenRes = state.exactTokens
+	scraper.ExcludeKeywords = search.ExcludeKeywords
+	scraper.RequireKeywords = search.RequireKeywords
+	scraper.RequireVideo = search.RequireVideo
+	scraper.TopRatedSellersOnly = search.TopRatedSellersOnly
+	scraper.EbayPlusOnly = search.EbayPlusOnly
+	scraper.OnlyBestOffer = search.OnlyBestOffer
+	scraper.ExcludeSponsored = search.ExcludeSponsored
+	scraper.ExcludeBundles = search.ExcludeBundles
+	scraper.OnlyBundles = search.OnlyBundles
+	scraper.MaxQuantityAvailable = search.MaxQuantityAvailable
+	scraper.MaxNormalizedPrice = search.MaxNormalizedPrice
+	scraper.MaxPricePerUnit = search.MaxPricePerUnit
+	scraper.SortByUnitPrice = search.SortByUnitPrice
+	scraper.MinSellerFeedback = search.MinSellerFeedback
+	scraper.LocationFilter = search.LocationFilter
+	scraper.OnlyFreeReturns = search.OnlyFreeReturns
+	scraper.StrictReturns = search.StrictReturns
+	scraper.AllowUnknownLocation = search.AllowUnknownLocation
+	scraper.Cache = state.cache
+	scraper.Client = client
+	scraper.Limiter = limiter
+	scraper.Metrics = metrics
+	scraper.Concurrency = concurrency
+	if config.Selectors != nil {
+		scraper.Selectors = *config.Selectors
+	}
+	if config.BaseCurrency != "" {
+		scraper.BaseCurrency = strings.ToUpper(config.BaseCurrency)
+		scraper.Rates = newStaticRates(config.ExchangeRates)
+	}
+	scraper.TraceTimings = config.TraceTimings
+	scraper.UserAgent = config.UserAgent
+	scraper.MaxRetries = config.MaxRetries
+	scraper.Backoff, _ = config.backoffStrategy()
+	return scraper
 }
 
 // main initializes and runs the continuous monitoring process
 func main() {
-	var config Config
-	config.CheckInterval = 300 // Default check interval
+	inventoryQuery := flag.String("inventory", "", "print the recorded inventory history for a query and exit")
+	configPath := flag.String("config", "config.json", "path to the configuration file")
+	useExisting := flag.Bool("use-existing", false, "use the existing config without prompting")
+	noPrompt := flag.Bool("no-prompt", false, "never prompt on stdin and exit if no usable config is found")
+	diff := flag.Bool("diff", false, "compare two findings files given as <old> <new> and exit")
+	importPath := flag.String("import-findings", "", "merge another findings.json into the local one and exit")
+	showStatus := flag.Bool("status", false, "show a live status display instead of scrolling output (terminals only)")
+	noColor := flag.Bool("no-color", false, "disable colored output even on a terminal")
+	dryRun := flag.Bool("dry-run", false, "print matching items without writing findings, logs or config")
+	summaryDate := flag.String("daily-summary", "", "write the summary for a day (YYYY-MM-DD) from its daily log and exit")
+	reportRange := flag.String("report", "", "print per-query statistics of the daily logs in a range (YYYY-MM-DD:YYYY-MM-DD) and exit")
+	once := flag.Bool("once", false, "run every search once and exit, with status 1 if a search failed")
+	flag.Parse()
+
+	// Escape codes are garbage in files and pipes, and NO_COLOR asks for plain output
+	if *noColor || os.Getenv("NO_COLOR") != "" || !isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+		color.NoColor = true
+	}
+
+	if *summaryDate != "" {
+		day, err := time.ParseInLocation("2006-01-02", *summaryDate, time.Local)
+		if err != nil {
+			log.Fatalf("Invalid date %q, expected YYYY-MM-DD", *summaryDate)
+		}
+		path, err := writeDailySummary(day)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Summary written to %s\n", path)
+		return
+	}
+
+	if *reportRange != "" {
+		start, end, err := parseDateRange(*reportRange)
+		if err != nil {
+			log.Fatal(err)
+		}
+		report, err := generateReport(start, end)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printReport(report)
+		return
+	}
+
+	if *importPath != "" {
+		if err := importFindings("findings.json", *importPath); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *diff {
+		if flag.NArg() != 2 {
+			log.Fatal("Usage: baycheck -diff <old findings> <new findings>")
+		}
+		if err := diffFindings(flag.Arg(0), flag.Arg(1)); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *inventoryQuery != "" {
+		if err := printInventory(*inventoryQuery); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	config := defaultConfig()
 
 	// Check if running in Docker
 	_, inDocker := os.LookupEnv("DOCKER_CONTAINER")
 
-	// Try to load existing config
-	if existingConfig, err := loadConfig(); err == nil {
+	// Without a terminal on stdin the prompts below would block forever
+	interactive := !*noPrompt &&
+		(isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd()))
+
+	// Try to load existing config. Headless starts must not fall back to the example
+	// searches of the template, and dry runs don't write it to disk.
+	existingConfig, err := loadConfig(*configPath, !*noPrompt, !*dryRun)
+	if *noPrompt && (err != nil || len(existingConfig.Searches) == 0) {
+		if err == nil {
+			err = fmt.Errorf("no searches configured")
+		}
+		log.Fatalf("-no-prompt is set but %s is not usable: %v", *configPath, err)
+	}
+
+	if err == nil {
 		if inDocker {
 			// In Docker, always use existing config
 			config = *existingConfig
 			fmt.Println("Running in Docker mode, using existing configuration")
+		} else if *useExisting || !interactive {
+			config = *existingConfig
+			fmt.Println("Using existing configuration")
 		} else {
 			fmt.Println("Found existing configuration.")
 			fmt.Print("Do you want to (1) use existing config, (2) add new searches, or (3) start fresh? [1/2/3]: ")
@@ -336,10 +1193,10 @@ func main() {
 			}
 		}
 	} else if inDocker {
-		log.Fatal("No config.json found and running in Docker. Please provide a config file.")
+		log.Fatalf("No %s found and running in Docker. Please provide a config file.", *configPath)
 	}
 
-	if len(config.Searches) == 0 && !inDocker {
+	if len(config.Searches) == 0 && !inDocker && interactive {
 		fmt.Print("How many items do you want to search for? ")
 		reader := bufio.NewReader(os.Stdin)
 		input, _ := reader.ReadString('\n')
@@ -362,56 +1219,345 @@ func main() {
 	}
 
 	// Save the configuration
-	if err := saveConfig(&config); err != nil {
+	config.DryRun = *dryRun
+	if config.DryRun {
+		fmt.Println("Dry run: configuration not saved")
+	} else if err := saveConfig(&config, *configPath); err != nil {
 		log.Printf("Warning: Could not save configuration: %v", err)
 	} else {
-		fmt.Println("Configuration saved to config.json")
+		fmt.Printf("Configuration saved to %s\n", *configPath)
 	}
 
-	// Continue with existing monitoring code
-	seenItems := make(map[string]map[string]bool)
-	for _, search := range config.Searches {
-		seenItems[search.Query] = make(map[string]bool)
+	if err := config.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
 
-	headerColor.Printf("Starting continuous monitoring for %d searches\n", len(config.Searches))
-	headerColor.Printf("Checking every %d seconds\n", config.CheckInterval)
-	headerColor.Printf("Saving results to findings.json and daily logs in ./logs/\n\n")
+	// Validate made sure the patterns compile
+	patterns, _ := compileItemIDPatterns(config.ItemIDPatterns)
+	setItemIDPatterns(patterns)
 
-	for {
-		for _, search := range config.Searches {
-			scraper := NewScraper()
-			scraper.ListingType = search.ListingType
-			scraper.MinPrice = search.MinPrice
-			scraper.MaxPrice = search.MaxPrice
-			scraper.MaxTimeLeft = search.MaxTimeLeft
-
-			results, err := scraper.ScrapeQuery(search.Query)
-			if err != nil {
-				log.Printf("Error scraping '%s': %v", search.Query, err)
-				continue
+	// In JSON mode the standard logger's lines become JSON entries as well
+	if config.LogFormat == "json" {
+		jsonLogs = true
+		log.SetFlags(0)
+		log.SetOutput(jsonLogWriter{})
+	}
+
+	client, err := newHTTPClient(&config)
+	if err != nil {
+		log.Fatalf("Invalid HTTP client configuration: %v", err)
+	}
+	// Shared by all searches, since pagination and retries can fire many requests per round
+	limiter := NewRateLimiter(config.MaxRequestsPerMinute)
+
+	notifier := newNotifier(&config, client)
+	// Validate made sure the storage setting is known
+	store, _ := newStore(&config)
+
+	// Restore previously found items so they aren't reported again, either from
+	// the recent daily logs or from the full findings history
+	seenItems := make(map[string]map[string]seenItem)
+	if config.SeenRehydrateDays <= 0 {
+		loaded, err := loadSeenItems()
+		if err != nil {
+			log.Printf("Warning: Could not load all seen items from findings.json: %v", err)
+		}
+		if loaded != nil {
+			seenItems = loaded
+		}
+	}
+
+	lowStockAlerted := make(map[string]map[string]bool)
+	endingSoonAlerted := make(map[string]map[string]bool)
+	lastTitles := make(map[string]map[string]string)
+	// trackSearches prepares the per-query state of searches that aren't tracked yet
+	trackSearches := func(searches []SearchConfig) {
+		for _, search := range searches {
+			if seenItems[search.Query] == nil {
+				seenItems[search.Query] = make(map[string]seenItem)
+			}
+			if lowStockAlerted[search.Query] == nil {
+				lowStockAlerted[search.Query] = make(map[string]bool)
+			}
+			if endingSoonAlerted[search.Query] == nil {
+				endingSoonAlerted[search.Query] = make(map[string]bool)
 			}
+			if lastTitles[search.Query] == nil {
+				lastTitles[search.Query] = make(map[string]string)
+			}
+		}
+	}
+	trackSearches(config.Searches)
 
-			var filteredResults []Item
-			for _, item := range results {
-				if (search.MinWatchers <= 0 || item.Watchers >= search.MinWatchers) &&
-					(search.MaxWatchers <= 0 || item.Watchers <= search.MaxWatchers) {
-					filteredResults = append(filteredResults, item)
+	if config.SeenRehydrateDays > 0 {
+		count := rehydrateSeenItems(config.SeenRehydrateDays, seenItems)
+		fmt.Printf("Restored %d seen items from the last %d days of logs\n", count, config.SeenRehydrateDays)
+	}
+
+	if !config.DryRun {
+		rotateLogs(&config)
+	}
+
+	inventory, err := loadInventory()
+	if err != nil {
+		log.Printf("Warning: Could not load inventory history: %v", err)
+		inventory = make(map[string][]InventoryPoint)
+	}
+
+	states := searchStates(&config, nil, nil)
+
+	// The status display only makes sense on a terminal, otherwise keep plain line output
+	var status *statusDisplay
+	if *showStatus && !jsonLogs && isatty.IsTerminal(os.Stdout.Fd()) {
+		status = newStatusDisplay(color.Output, config.Searches)
+		color.Output = status
+		log.SetOutput(status)
+	}
+
+	if jsonLogs {
+		logInfo("", "", fmt.Sprintf("Starting continuous monitoring for %d searches every %d seconds", len(config.Searches), config.CheckInterval))
+	} else {
+		headerColor.Printf("Starting continuous monitoring for %d searches\n", len(config.Searches))
+		headerColor.Printf("Checking every %d seconds\n", config.CheckInterval)
+		if config.DryRun {
+			headerColor.Printf("Dry run: results are only printed, no files are written\n\n")
+		} else {
+			headerColor.Printf("Saving results to findings.json and daily logs in ./logs/\n\n")
+		}
+	}
+
+	// Stop after the current search on SIGINT/SIGTERM so no findings entry is
+	// cut off mid-write. A second signal terminates immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		if jsonLogs {
+			logInfo("", "", "Shutting down, aborting the current requests")
+		} else {
+			headerColor.Printf("\nShutting down, aborting the current requests, interrupt again to quit immediately\n")
+		}
+	}()
+
+	// Serve the findings saved to findings.json, read anew for every request
+	var server *findingsServer
+	var metrics *Metrics
+	if config.HTTPAddr != "" {
+		server = newFindingsServer("findings.json")
+		if config.Metrics {
+			metrics = NewMetrics()
+		}
+		server.serve(ctx, config.HTTPAddr, metrics)
+	}
+	concurrency := config.concurrencyController(metrics)
+
+	firstCycle := true
+	currentDay := time.Now()
+	sessionItems := 0
+	configModTime, _ := fileModTime(*configPath)
+	// With -once the run counts as failed unless a full round completes without errors
+	onceFailed := *once
+	for ctx.Err() == nil {
+		// An external scheduler calls again later instead of waiting for the next window
+		if *once && !isActive(config.ActiveWindows, time.Now()) {
+			if jsonLogs {
+				logInfo("", "", "Outside active windows, nothing to do")
+			} else {
+				headerColor.Printf("Outside active windows, nothing to do\n")
+			}
+			onceFailed = false
+			break
+		}
+		waitForActiveWindow(ctx, config.ActiveWindows)
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Pick up edits to the config file, keeping the state of known searches
+		if reloaded := reloadConfig(*configPath, &configModTime); reloaded != nil {
+			reloaded.DryRun = config.DryRun
+			if reloaded.MaxRequestsPerMinute != config.MaxRequestsPerMinute {
+				limiter = NewRateLimiter(reloaded.MaxRequestsPerMinute)
+			}
+			if concurrencySettingsChanged(&config, reloaded) {
+				concurrency = reloaded.concurrencyController(metrics)
+			}
+			if clientSettingsChanged(&config, reloaded) {
+				if rebuilt, err := newHTTPClient(reloaded); err != nil {
+					log.Printf("Keeping the previous HTTP client: %v", err)
+				} else {
+					client = rebuilt
 				}
 			}
+			if keys := restartOnlyChanges(&config, reloaded); len(keys) > 0 {
+				log.Printf("Changes to %s take effect after a restart", strings.Join(keys, ", "))
+			}
+			states = searchStates(reloaded, config.Searches, states)
+			config = *reloaded
+			trackSearches(config.Searches)
+			notifier = newNotifier(&config, client)
+			store, _ = newStore(&config)
+			patterns, _ := compileItemIDPatterns(config.ItemIDPatterns)
+			setItemIDPatterns(patterns)
+			status.setSearches(config.Searches)
+			if jsonLogs {
+				logInfo("", "", fmt.Sprintf("Reloaded %s, now monitoring %d searches", *configPath, len(config.Searches)))
+			} else {
+				headerColor.Printf("Reloaded %s, now monitoring %d searches\n", *configPath, len(config.Searches))
+			}
+		}
+
+		// Summarize the previous day once the date rolls over, a dry run has nothing logged
+		if now := time.Now(); now.Format("2006-01-02") != currentDay.Format("2006-01-02") {
+			if !config.DryRun {
+				if path, err := writeDailySummary(currentDay); err != nil {
+					log.Printf("Error writing daily summary: %v", err)
+				} else if jsonLogs {
+					logInfo("", "", fmt.Sprintf("Daily summary written to %s", path))
+				} else {
+					headerColor.Printf("Daily summary written to %s\n", path)
+				}
+			}
+			currentDay = now
+			if !config.DryRun {
+				rotateLogs(&config)
+			}
+		}
+
+		// Searches are scraped by a pool of workers while result handling is
+		// serialized, so file writes don't interleave and each query's output stays together
+		var mu sync.Mutex
+		cycleOK := true
+		summaryLines := make([]string, len(config.Searches))
+
+		// scrapeSearch fetches the results of a search, reporting whether they are complete
+		// and false if there is nothing to handle
+		scrapeSearch := func(i int, search SearchConfig) (results []Item, complete, ok bool) {
+			status.setState(i, "scraping")
+			scraper := newSearchScraper(&config, search, states[i], client, limiter, metrics, concurrency)
 
+			if search.CountOnly {
+				full, err := needsFullScrape(ctx, scraper, search, states[i].count)
+				if err != nil && ctx.Err() != nil {
+					return nil, false, false
+				}
+				if err != nil {
+					if jsonLogs {
+						logError(search.Query, fmt.Sprintf("Error checking result count: %v", err))
+					} else {
+						log.Printf("Error checking result count for '%s': %v", search.Query, err)
+					}
+					status.setState(i, "error")
+					mu.Lock()
+					cycleOK = false
+					mu.Unlock()
+					return nil, false, false
+				}
+				if !full {
+					server.setLastCheck(time.Now())
+					status.setState(i, "count unchanged")
+					if jsonLogs {
+						logInfo(search.Query, "", "Result count unchanged")
+						return nil, false, false
+					}
+					headerColor.Printf("[%s] Query '%s': Result count unchanged\n",
+						time.Now().Format("2006-01-02 15:04:05"),
+						search.Query)
+					return nil, false, false
+				}
+			}
+
+			results, err := scraper.ScrapeQueryContext(ctx, search.Query)
+			if errors.Is(err, ErrNoResults) {
+				// An empty search is a successful check, unlike a page that couldn't be read
+				if jsonLogs {
+					logInfo(search.Query, "", "No matches")
+				} else {
+					headerColor.Printf("[%s] Query '%s': No matches\n",
+						time.Now().Format("2006-01-02 15:04:05"),
+						search.Query)
+				}
+				err = nil
+			}
+			if err == nil {
+				server.setLastCheck(time.Now())
+			} else if ctx.Err() != nil {
+				// Shutting down, keep what was fetched before the requests were aborted
+				return results, false, len(results) > 0
+			} else {
+				problem := "Error scraping"
+				if errors.Is(err, ErrNoListings) {
+					problem = "Possible scraper breakage"
+				}
+				if jsonLogs {
+					logError(search.Query, fmt.Sprintf("%s: %v", problem, err))
+				} else {
+					log.Printf("%s for '%s': %v", problem, search.Query, err)
+				}
+				status.setState(i, "error")
+				mu.Lock()
+				cycleOK = false
+				mu.Unlock()
+				if len(results) == 0 {
+					return nil, false, false
+				}
+			}
+			return results, err == nil, true
+		}
+
+		// handleResults saves, reports and tracks the results of a search, called with mu held.
+		// It returns the saved entries to be notified about after unlocking.
+		handleResults := func(i int, search SearchConfig, results []Item, complete bool) []SavedItem {
+			// Report title changes before saving, which records the new titles
+			if config.TrackTitleChanges {
+				reportTitleChanges(results, search.Query, lastTitles[search.Query])
+			}
 			// Save new items
-			saveNewItems(filteredResults, search.Query, seenItems[search.Query])
+			newItems, saved := saveNewItems(&config, store, results, search.Query, seenItems[search.Query], lastTitles[search.Query])
+			// Expire after saving, so items still listed have just been marked as seen
+			if config.DedupTTLDays > 0 {
+				expireSeenItems(seenItems[search.Query], time.Duration(config.DedupTTLDays)*24*time.Hour, time.Now())
+			}
+			// A dry run writes nothing, so the server keeps its findings
+			if config.DryRun {
+				server.addUnsaved(saved)
+			}
+			metrics.observeItems(search.Query, newItems)
+			sessionItems += newItems
+			alertLowStock(results, search.Query, search.LowStockThreshold, lowStockAlerted[search.Query])
+			alertEndingSoon(results, search, endingSoonAlerted[search.Query])
+			summaryLines[i] = fmt.Sprintf("%s: %d matching, %d new\n", search.Query, len(results), newItems)
 
-			// Print results for this search
-			now := time.Now().Format("2006-01-02 15:04:05")
-			newItems := 0
-			for _, item := range filteredResults {
-				if !seenItems[search.Query][item.URL] {
-					newItems++
+			// Track the total number of matching listings, which partial results would understate
+			if complete {
+				if previous, changed := recordInventory(inventory, search.Query, len(results), config.InventoryChangeThreshold); changed {
+					if previous >= 0 && jsonLogs {
+						logInfo(search.Query, "", fmt.Sprintf("Inventory changed from %d to %d matching items", previous, len(results)))
+					} else if previous >= 0 {
+						headerColor.Printf("Query '%s': Inventory changed from %d to %d matching items\n",
+							search.Query,
+							previous,
+							len(results))
+					}
+					if !config.DryRun {
+						if err := saveInventory(inventory); err != nil {
+							log.Printf("Error saving inventory history: %v", err)
+						}
+					}
 				}
 			}
 
+			// Print results for this search
+			status.setResult(i, len(results), newItems)
+			if status != nil {
+				return saved
+			}
+			if jsonLogs {
+				logSearchResult(search.Query, newItems)
+				return saved
+			}
+			now := time.Now().Format("2006-01-02 15:04:05")
+
 			if newItems > 0 {
 				headerColor.Printf("\n[%s] Query '%s': Found %d new items!\n",
 					now,
@@ -422,8 +1568,111 @@ func main() {
 					now,
 					search.Query)
 			}
+			return saved
+		}
+
+		// With adaptive concurrency there are MaxConcurrency workers, but only as many
+		// as the controller allows scrape at the same time
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < config.MaxConcurrency; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					search := config.Searches[i]
+					// A panicking search is restarted instead of taking down the monitor,
+					// so the locks and the concurrency slot are released by defer
+					ok := superviseSearch(ctx, search.Query, searchRestartBackoff, metrics, func() {
+						results, complete, ok := func() ([]Item, bool, bool) {
+							concurrency.Acquire()
+							defer concurrency.Release()
+							return scrapeSearch(i, search)
+						}()
+						if !ok {
+							return
+						}
+						saved, n := func() ([]SavedItem, Notifier) {
+							mu.Lock()
+							defer mu.Unlock()
+							saved := handleResults(i, search, results, complete)
+							if !config.NotifyShippingChanges {
+								saved = withoutShippingChanges(saved)
+							}
+							if config.DryRun {
+								return saved, nil
+							}
+							return saved, notifier
+						}()
+						// Notifiers can be slow, so they mustn't hold up the other workers
+						notifySaved(n, saved)
+					})
+					if !ok {
+						status.setState(i, "crashed")
+						mu.Lock()
+						cycleOK = false
+						mu.Unlock()
+					}
+				}
+			}()
+		}
+
+		// Hand out the due searches in the order of their start offsets from the round start
+		roundStart := time.Now()
+		type start struct {
+			i  int
+			at time.Time
+		}
+		var starts []start
+		for i := range config.Searches {
+			if !states[i].nextRun.After(roundStart) {
+				starts = append(starts, start{i, roundStart.Add(searchOffset(&config, i, firstCycle))})
+			}
+		}
+		sort.SliceStable(starts, func(a, b int) bool { return starts[a].at.Before(starts[b].at) })
+		var ran []int
+		for _, s := range starts {
+			if wait := time.Until(s.at); wait > 0 {
+				sleepContext(ctx, wait)
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			jobs <- s.i
+			ran = append(ran, s.i)
+		}
+		close(jobs)
+		wg.Wait()
+		if err := flushNotifier(notifier); err != nil {
+			log.Printf("Error sending notification: %v", err)
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+		if cycleOK && config.HeartbeatURL != "" {
+			sendHeartbeat(client, config.HeartbeatURL, strings.Join(summaryLines, ""))
+		}
+		if *once {
+			onceFailed = !cycleOK
+			break
 		}
 
-		time.Sleep(time.Duration(config.CheckInterval) * time.Second)
+		firstCycle = false
+		now := time.Now()
+		for _, i := range ran {
+			states[i].nextRun = now.Add(searchInterval(&config, config.Searches[i]))
+			status.setNextRun(i, states[i].nextRun)
+		}
+		sleepContext(ctx, time.Until(nextDue(states)))
+	}
+
+	if jsonLogs {
+		logInfo("", "", fmt.Sprintf("Stopped monitoring, found %d new items this session", sessionItems))
+	} else {
+		headerColor.Printf("Stopped monitoring, found %d new items this session\n", sessionItems)
+	}
+	if onceFailed {
+		os.Exit(1)
 	}
 }
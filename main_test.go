@@ -0,0 +1,136 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClientSettingsChanged(t *testing.T) {
+	base := &Config{ProxyURL: "http://proxy:8080", TimeoutSeconds: 30}
+	tests := []struct {
+		name string
+		edit func(c *Config)
+		want bool
+	}{
+		{"unchanged", func(c *Config) {}, false},
+		{"proxy", func(c *Config) { c.ProxyURL = "http://other:8080" }, true},
+		{"proxy list", func(c *Config) { c.ProxyURLs = []string{"http://second:8080"} }, true},
+		{"tls", func(c *Config) { c.TLS = &TLSConfig{} }, true},
+		{"cookie file", func(c *Config) { c.CookieFile = "cookies.txt" }, true},
+		{"timeout", func(c *Config) { c.TimeoutSeconds = 10 }, true},
+		{"unrelated", func(c *Config) { c.CheckInterval = 60 }, false},
+	}
+	for _, tt := range tests {
+		reloaded := *base
+		tt.edit(&reloaded)
+		if got := clientSettingsChanged(base, &reloaded); got != tt.want {
+			t.Errorf("%s: clientSettingsChanged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRestartOnlyChanges(t *testing.T) {
+	old := &Config{HTTPAddr: ":8080", LogFormat: "text"}
+	reloaded := &Config{HTTPAddr: ":9090", Metrics: true, LogFormat: "text", CheckInterval: 60}
+	want := []string{"http_addr", "metrics"}
+	if got := restartOnlyChanges(old, reloaded); !reflect.DeepEqual(got, want) {
+		t.Errorf("restartOnlyChanges = %v, want %v", got, want)
+	}
+	if got := restartOnlyChanges(old, old); got != nil {
+		t.Errorf("restartOnlyChanges of an unchanged config = %v, want nil", got)
+	}
+}
+
+func TestSaveNewItemsShippingChanges(t *testing.T) {
+	item := func(price, shipping float64) Item {
+		return Item{ID: "1", Title: "Switch OLED", PriceValue: price, PriceCents: toCents(price), ShippingCost: shipping,
+			URL: "https://www.ebay.de/itm/1"}
+	}
+	tests := []struct {
+		name         string
+		track        bool
+		first, again Item
+		wantSaved    bool
+		wantShipping float64 // previous shipping of the event, -1 for none
+		wantPrice    float64
+	}{
+		{"shipping dropped to free", true, item(100, 4.99), item(100, 0), true, 4.99, 0},
+		{"free shipping ended", true, item(100, 0), item(100, 5.49), true, 0, 0},
+		{"not tracked", false, item(100, 4.99), item(100, 0), false, -1, 0},
+		{"unchanged", true, item(100, 4.99), item(100, 4.99), false, -1, 0},
+		{"unknown shipping", true, item(100, -1), item(100, 4.99), false, -1, 0},
+		{"price drop and shipping change", true, item(100, 4.99), item(90, 0), true, 4.99, 100},
+		{"price drop only", true, item(100, 4.99), item(90, 4.99), true, -1, 100},
+	}
+	for _, tt := range tests {
+		config := &Config{DryRun: true, TrackShippingChanges: tt.track}
+		seen := make(map[string]seenItem)
+		titles := make(map[string]string)
+		saveNewItems(config, nil, []Item{tt.first}, "switch", seen, titles)
+		newItems, saved := saveNewItems(config, nil, []Item{tt.again}, "switch", seen, titles)
+
+		if newItems != 0 {
+			t.Errorf("%s: %d new items, want 0", tt.name, newItems)
+		}
+		if len(saved) > 1 || (len(saved) == 1) != tt.wantSaved {
+			t.Errorf("%s: saved %+v, want saved=%v", tt.name, saved, tt.wantSaved)
+			continue
+		}
+		if !tt.wantSaved {
+			continue
+		}
+		event := saved[0]
+		if event.PreviousPrice != tt.wantPrice {
+			t.Errorf("%s: previous price %.2f, want %.2f", tt.name, event.PreviousPrice, tt.wantPrice)
+		}
+		switch {
+		case tt.wantShipping < 0 && event.PreviousShipping != nil:
+			t.Errorf("%s: previous shipping %.2f, want none", tt.name, *event.PreviousShipping)
+		case tt.wantShipping >= 0 && (event.PreviousShipping == nil || *event.PreviousShipping != tt.wantShipping):
+			t.Errorf("%s: previous shipping %v, want %.2f", tt.name, event.PreviousShipping, tt.wantShipping)
+		}
+		if event.isNew() {
+			t.Errorf("%s: change event counts as a new item", tt.name)
+		}
+	}
+}
+
+func TestSearchStatesCompileExactTokensOnce(t *testing.T) {
+	config := &Config{Searches: []SearchConfig{{Query: "macbook", ExactTokens: []string{"A1706", " "}}}}
+	states := searchStates(config, nil, nil)
+	if got := len(states[0].exactTokens); got != 1 {
+		t.Fatalf("compiled %d exact tokens, want 1", got)
+	}
+
+	// An unchanged search keeps its compiled patterns across reloads
+	reloaded := &Config{Searches: []SearchConfig{{Query: "macbook", ExactTokens: []string{"A1706", " "}}}}
+	next := searchStates(reloaded, config.Searches, states)
+	if next[0].exactTokens[0] != states[0].exactTokens[0] {
+		t.Error("unchanged search recompiled its exact tokens")
+	}
+
+	scraper := newSearchScraper(reloaded, reloaded.Searches[0], next[0], nil, nil, nil, nil)
+	if !scraper.matchesExactTokens("MacBook Pro A1706") || scraper.matchesExactTokens("MacBook Pro A1707") {
+		t.Error("scraper doesn't use the search's exact tokens")
+	}
+}
+
+func TestSearchOffset(t *testing.T) {
+	config := &Config{
+		StartupStaggerSeconds: 30,
+		Searches:              []SearchConfig{{Query: "a"}, {Query: "b"}, {Query: "c"}},
+	}
+	for i, want := range []time.Duration{0, 10 * time.Second, 20 * time.Second} {
+		if got := searchOffset(config, i, true); got != want {
+			t.Errorf("first cycle offset of search %d = %v, want %v", i, got, want)
+		}
+	}
+
+	config.SearchJitterSeconds = 5
+	for i := range config.Searches {
+		if got := searchOffset(config, i, false); got < 0 || got >= 5*time.Second {
+			t.Errorf("jitter offset of search %d = %v, want within [0, 5s)", i, got)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Package main provides the regional eBay sites the scraper can target.
+Each marketplace determines the host and how prices and times are formatted.
+*/
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultMarketplace is used when a search doesn't specify one
+const DefaultMarketplace = "ebay.de"
+
+/*
+Marketplace describes the locale of a regional eBay site.
+DecimalComma is set for sites writing prices as "1.234,56" rather than "1,234.56",
+German for sites using German labels such as "5T 12Std" and "Neues Angebot",
+EbayPlus for sites offering the eBay Plus program. Currency is the site's
+default currency code.
+*/
+type Marketplace struct {
+	Host           string
+	AcceptLanguage string
+	Currency       string
+	DecimalComma   bool
+	German         bool
+	EbayPlus       bool
+}
+
+// Supported marketplaces keyed by domain
+var marketplaces = map[string]Marketplace{
+	"ebay.de":     {Host: "www.ebay.de", AcceptLanguage: "de-DE,de;q=0.9,en;q=0.5", Currency: "EUR", DecimalComma: true, German: true, EbayPlus: true},
+	"ebay.at":     {Host: "www.ebay.at", AcceptLanguage: "de-AT,de;q=0.9,en;q=0.5", Currency: "EUR", DecimalComma: true, German: true},
+	"ebay.com":    {Host: "www.ebay.com", AcceptLanguage: "en-US,en;q=0.9", Currency: "USD"},
+	"ebay.co.uk":  {Host: "www.ebay.co.uk", AcceptLanguage: "en-GB,en;q=0.9", Currency: "GBP"},
+	"ebay.ie":     {Host: "www.ebay.ie", AcceptLanguage: "en-IE,en;q=0.9", Currency: "EUR"},
+	"ebay.ca":     {Host: "www.ebay.ca", AcceptLanguage: "en-CA,en;q=0.9", Currency: "CAD"},
+	"ebay.com.au": {Host: "www.ebay.com.au", AcceptLanguage: "en-AU,en;q=0.9", Currency: "AUD"},
+}
+
+// lookupMarketplace returns the marketplace for a domain, defaulting to ebay.de when empty
+func lookupMarketplace(name string) (Marketplace, error) {
+	name = marketplaceDomain(name)
+	if name == "" {
+		name = DefaultMarketplace
+	}
+	if marketplace, ok := marketplaces[name]; ok {
+		return marketplace, nil
+	}
+
+	supported := make([]string, 0, len(marketplaces))
+	for domain := range marketplaces {
+		supported = append(supported, domain)
+	}
+	sort.Strings(supported)
+	return Marketplace{}, fmt.Errorf("unsupported marketplace %q, expected one of %s", name, strings.Join(supported, ", "))
+}
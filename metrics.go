@@ -0,0 +1,161 @@
+/*
+Package main provides Prometheus metrics for the monitor.
+The metrics are registered on their own registry and served with the
+Prometheus client library, so they only exist when metrics are enabled.
+*/
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+/*
+Metrics counts scrapes, errors, found items and HTTP responses, and with
+timing traces enabled records the phases of each request in histograms.
+All methods are safe for concurrent use and do nothing on nil Metrics,
+so scrapers without metrics don't pay for them.
+*/
+type Metrics struct {
+	registry     *prometheus.Registry
+	scrapes      *prometheus.CounterVec
+	scrapeErrors *prometheus.CounterVec
+	itemsFound   *prometheus.CounterVec
+	responses    *prometheus.CounterVec
+	restarts     *prometheus.CounterVec
+	lastSuccess  prometheus.Gauge
+	concurrency  prometheus.Gauge
+
+	dnsSeconds     prometheus.Histogram
+	connectSeconds prometheus.Histogram
+	tlsSeconds     prometheus.Histogram
+	ttfbSeconds    prometheus.Histogram
+}
+
+// NewMetrics creates the metrics on a registry of their own
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		scrapes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "baycheck_scrapes_total",
+			Help: "Scrapes per query.",
+		}, []string{"query"}),
+		scrapeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "baycheck_scrape_errors_total",
+			Help: "Failed scrapes per query.",
+		}, []string{"query"}),
+		itemsFound: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "baycheck_items_found_total",
+			Help: "New items found per query.",
+		}, []string{"query"}),
+		responses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "baycheck_http_responses_total",
+			Help: "HTTP responses from eBay by status code.",
+		}, []string{"code"}),
+		restarts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "baycheck_search_restarts_total",
+			Help: "Restarts of searches after a panic, per query.",
+		}, []string{"query"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "baycheck_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful scrape, 0 if none yet.",
+		}),
+		concurrency: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "baycheck_concurrency",
+			Help: "Searches allowed to be scraped at the same time, adapted with adaptive_concurrency.",
+		}),
+		dnsSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "baycheck_request_dns_seconds",
+			Help: "DNS lookup time of requests to eBay, with trace_timings enabled.",
+		}),
+		connectSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "baycheck_request_connect_seconds",
+			Help: "TCP connect time of requests to eBay, with trace_timings enabled.",
+		}),
+		tlsSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "baycheck_request_tls_seconds",
+			Help: "TLS handshake time of requests to eBay, with trace_timings enabled.",
+		}),
+		ttfbSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "baycheck_request_ttfb_seconds",
+			Help: "Time from sending a request to eBay to its first response byte, with trace_timings enabled.",
+		}),
+	}
+	m.registry.MustRegister(m.scrapes, m.scrapeErrors, m.itemsFound, m.responses, m.restarts, m.lastSuccess, m.concurrency,
+		m.dnsSeconds, m.connectSeconds, m.tlsSeconds, m.ttfbSeconds)
+	return m
+}
+
+// observeScrape records a scrape of a query and whether it failed
+func (m *Metrics) observeScrape(query string, err error) {
+	if m == nil {
+		return
+	}
+	m.scrapes.WithLabelValues(query).Inc()
+	// Adding 0 still creates the sample, so alerts see queries that never failed
+	failed := m.scrapeErrors.WithLabelValues(query)
+	if err != nil {
+		failed.Inc()
+	} else {
+		failed.Add(0)
+		m.lastSuccess.SetToCurrentTime()
+	}
+}
+
+// observeItems adds newly found items for a query
+func (m *Metrics) observeItems(query string, count int) {
+	if m == nil {
+		return
+	}
+	m.itemsFound.WithLabelValues(query).Add(float64(count))
+}
+
+// observeResponse records the status code of an HTTP response from eBay
+func (m *Metrics) observeResponse(code int) {
+	if m == nil {
+		return
+	}
+	m.responses.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+// observeRestart records a restart of a query's search after a panic
+func (m *Metrics) observeRestart(query string) {
+	if m == nil {
+		return
+	}
+	m.restarts.WithLabelValues(query).Inc()
+}
+
+// setConcurrency records the number of searches allowed to run at the same time
+func (m *Metrics) setConcurrency(limit int) {
+	if m == nil {
+		return
+	}
+	m.concurrency.Set(float64(limit))
+}
+
+// observeTimings records the phases of a traced request attempt. Phases that didn't
+// happen, e.g. DNS and TLS on a reused connection, aren't recorded.
+func (m *Metrics) observeTimings(t *requestTimings) {
+	if m == nil {
+		return
+	}
+	observe := func(histogram prometheus.Histogram, d time.Duration) {
+		if d > 0 {
+			histogram.Observe(d.Seconds())
+		}
+	}
+	observe(m.dnsSeconds, span(t.dnsStart, t.dnsDone))
+	observe(m.connectSeconds, span(t.connectStart, t.connectDone))
+	observe(m.tlsSeconds, span(t.tlsStart, t.tlsDone))
+	observe(m.ttfbSeconds, span(t.start, t.firstByte))
+}
+
+// handler serves the metrics in the Prometheus text format
+func (m *Metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.observeScrape("switch", nil)
+	metrics.observeScrape("switch", nil)
+	metrics.observeScrape(`lego "technic"`, errors.New("status code error: 503"))
+	metrics.observeItems("switch", 3)
+	metrics.observeResponse(200)
+	metrics.observeResponse(503)
+
+	server := httptest.NewServer(metrics.handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the Prometheus text format", contentType)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		t.Fatalf("/metrics is not in the Prometheus text format: %v", err)
+	}
+
+	// value returns the sample of a metric family with the given label value
+	value := func(name, label string) float64 {
+		t.Helper()
+		family, ok := families[name]
+		if !ok {
+			t.Fatalf("metric %s missing", name)
+		}
+		for _, metric := range family.GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				if pair.GetValue() == label {
+					if metric.GetCounter() != nil {
+						return metric.GetCounter().GetValue()
+					}
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+		t.Fatalf("metric %s has no sample labelled %q", name, label)
+		return 0
+	}
+	tests := []struct {
+		name  string
+		label string
+		want  float64
+	}{
+		{"baycheck_scrapes_total", "switch", 2},
+		{"baycheck_scrapes_total", `lego "technic"`, 1},
+		{"baycheck_scrape_errors_total", "switch", 0},
+		{"baycheck_scrape_errors_total", `lego "technic"`, 1},
+		{"baycheck_items_found_total", "switch", 3},
+		{"baycheck_http_responses_total", "200", 1},
+		{"baycheck_http_responses_total", "503", 1},
+	}
+	for _, tt := range tests {
+		if got := value(tt.name, tt.label); got != tt.want {
+			t.Errorf("%s{%q} = %v, want %v", tt.name, tt.label, got, tt.want)
+		}
+	}
+
+	lastSuccess := families["baycheck_last_success_timestamp_seconds"]
+	if lastSuccess == nil || len(lastSuccess.GetMetric()) != 1 || lastSuccess.GetMetric()[0].GetGauge().GetValue() <= 0 {
+		t.Errorf("baycheck_last_success_timestamp_seconds = %v, want the time of the last success", lastSuccess)
+	}
+}
+
+func TestTraceTimingsPerAttempt(t *testing.T) {
+	// A failed attempt followed by a successful retry should both be recorded
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	metrics := NewMetrics()
+	scraper := NewScraper()
+	scraper.TraceTimings = true
+	scraper.Metrics = metrics
+	scraper.Backoff = ConstantBackoff{}
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := scraper.do(req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+
+	families, err := metrics.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	counts := make(map[string]uint64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			if histogram := metric.GetHistogram(); histogram != nil {
+				counts[family.GetName()] = histogram.GetSampleCount()
+			}
+		}
+	}
+	if got := counts["baycheck_request_ttfb_seconds"]; got != 2 {
+		t.Errorf("ttfb samples = %d, want one per attempt (2)", got)
+	}
+	for _, name := range []string{"baycheck_request_dns_seconds", "baycheck_request_connect_seconds", "baycheck_request_tls_seconds", "baycheck_request_ttfb_seconds"} {
+		if _, ok := counts[name]; !ok {
+			t.Errorf("histogram %s is not exported", name)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Package main provides notifications for newly found items.
+A webhook notifier posts each item to a configured URL such as Slack, Discord or ntfy,
+an email notifier sends it through SMTP and a Telegram notifier to a chat.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+/*
+Notifier is informed about every genuinely new item found for a query.
+Errors are reported to the caller, which logs them without aborting the run.
+*/
+type Notifier interface {
+	Notify(item Item, query string) error
+}
+
+/*
+ChangeNotifier is a Notifier that is also informed about changes of known items: price
+drops, recognizable by a non-zero PreviousPrice, and with notify_shipping_changes shipping
+cost changes, recognizable by a PreviousShipping. Changes need the previous values, which
+Notify has no room for, so notifiers only implementing Notify aren't told about them.
+*/
+type ChangeNotifier interface {
+	Notifier
+	NotifyChange(saved SavedItem) error
+}
+
+// newSavedItem describes a new item found now for a query, as notifiers pass it on
+func newSavedItem(item Item, query string) SavedItem {
+	return SavedItem{Item: item, Found: time.Now(), QueryTerm: query}
+}
+
+/*
+multiNotifier passes each item to several notifiers, so one failing
+doesn't keep the others from being notified.
+*/
+type multiNotifier []Notifier
+
+// Notify informs every notifier and returns their combined errors
+func (m multiNotifier) Notify(item Item, query string) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(item, query); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyChange informs every notifier that handles changes and returns their combined errors
+func (m multiNotifier) NotifyChange(saved SavedItem) error {
+	var errs []error
+	for _, notifier := range m {
+		if changes, ok := notifier.(ChangeNotifier); ok {
+			if err := changes.NotifyChange(saved); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flush sends the items collected by the batching notifiers
+func (m multiNotifier) Flush() error {
+	var errs []error
+	for _, notifier := range m {
+		if err := flushNotifier(notifier); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notifySaved passes the saved entries to the notifier, new items to Notify and changes of
+// known items to NotifyChange if the notifier implements it, logging failures.
+// A nil notifier is skipped.
+func notifySaved(notifier Notifier, saved []SavedItem) {
+	if notifier == nil {
+		return
+	}
+	changes, _ := notifier.(ChangeNotifier)
+	for _, entry := range saved {
+		var err error
+		switch {
+		case entry.isNew():
+			err = notifier.Notify(entry.Item, entry.QueryTerm)
+		case changes != nil:
+			err = changes.NotifyChange(entry)
+		}
+		if err != nil {
+			log.Printf("Error sending notification: %v", err)
+		}
+	}
+}
+
+// withoutShippingChanges drops the entries only recording a shipping change, which are
+// saved but not notified about unless notify_shipping_changes is set
+func withoutShippingChanges(saved []SavedItem) []SavedItem {
+	var kept []SavedItem
+	for _, entry := range saved {
+		if entry.PreviousShipping == nil || entry.PreviousPrice != 0 {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// newNotifier creates the notifiers enabled in the configuration, nil if there are none
+func newNotifier(config *Config, client *http.Client) Notifier {
+	// Validate made sure the templates parse
+	templates, _ := parseTemplates(config.Templates)
+	var notifiers multiNotifier
+	if config.WebhookURL != "" {
+		notifiers = append(notifiers, &WebhookNotifier{URL: config.WebhookURL, Client: client,
+			SchemaVersion: config.WebhookSchemaVersion, Template: templates.Webhook})
+	}
+	if config.Email != nil {
+		notifiers = append(notifiers, &EmailNotifier{Config: config.Email, Templates: templates})
+	}
+	if config.Telegram != nil {
+		notifier := newTelegramNotifier(config.Telegram, client)
+		notifier.Templates = templates
+		notifiers = append(notifiers, notifier)
+	}
+	switch len(notifiers) {
+	case 0:
+		return nil
+	case 1:
+		return notifiers[0]
+	}
+	return notifiers
+}
+
+/*
+WebhookNotifier posts each new item, price drop or shipping change to URL as JSON
+in the payload of SchemaVersion, the latest when 0, or as rendered by Template if set.
+*/
+type WebhookNotifier struct {
+	URL           string
+	Client        *http.Client
+	SchemaVersion int
+	Template      *template.Template
+}
+
+// Notify posts a new item to the webhook URL
+func (w *WebhookNotifier) Notify(item Item, query string) error {
+	return w.post(newSavedItem(item, query))
+}
+
+// NotifyChange posts a price drop or shipping change to the webhook URL
+func (w *WebhookNotifier) NotifyChange(saved SavedItem) error {
+	return w.post(saved)
+}
+
+// post sends the entry as a JSON POST request to the webhook URL
+func (w *WebhookNotifier) post(saved SavedItem) error {
+	body, err := w.body(saved)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %s", resp.Status)
+	}
+	return nil
+}
+
+// body renders the request body of an entry
+func (w *WebhookNotifier) body(saved SavedItem) ([]byte, error) {
+	if w.Template != nil {
+		text, err := executeTemplate(w.Template, saved)
+		return []byte(text), err
+	}
+	payload, err := webhookPayload(saved, w.SchemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newItemsOnly records the new items it is told about, like a notifier predating change notifications
+type newItemsOnly struct {
+	items []string
+}
+
+func (n *newItemsOnly) Notify(item Item, query string) error {
+	n.items = append(n.items, query+":"+item.ID)
+	return nil
+}
+
+// allChanges records new items and changes
+type allChanges struct {
+	newItemsOnly
+	changes []string
+}
+
+func (n *allChanges) NotifyChange(saved SavedItem) error {
+	n.changes = append(n.changes, saved.QueryTerm+":"+saved.Item.ID)
+	return nil
+}
+
+func TestNotifySavedDispatch(t *testing.T) {
+	shipping := 4.99
+	saved := []SavedItem{
+		{Item: Item{ID: "1"}, QueryTerm: "switch"},
+		{Item: Item{ID: "2"}, QueryTerm: "switch", PreviousPrice: 120},
+		{Item: Item{ID: "3"}, QueryTerm: "lego", PreviousShipping: &shipping},
+		{Item: Item{ID: "4"}, QueryTerm: "lego"},
+	}
+
+	simple := &newItemsOnly{}
+	full := &allChanges{}
+	notifySaved(multiNotifier{simple, full}, saved)
+
+	if want := []string{"switch:1", "lego:4"}; !reflect.DeepEqual(simple.items, want) {
+		t.Errorf("Notify-only notifier got %v, want %v", simple.items, want)
+	}
+	if want := []string{"switch:1", "lego:4"}; !reflect.DeepEqual(full.items, want) {
+		t.Errorf("change notifier got new items %v, want %v", full.items, want)
+	}
+	if want := []string{"switch:2", "lego:3"}; !reflect.DeepEqual(full.changes, want) {
+		t.Errorf("change notifier got changes %v, want %v", full.changes, want)
+	}
+
+	// A single notifier without NotifyChange only gets the new items
+	alone := &newItemsOnly{}
+	notifySaved(alone, saved)
+	if want := []string{"switch:1", "lego:4"}; !reflect.DeepEqual(alone.items, want) {
+		t.Errorf("single notifier got %v, want %v", alone.items, want)
+	}
+}
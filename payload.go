@@ -0,0 +1,115 @@
+/*
+Package main provides the versioned payloads of the webhook notifier.
+The schema version is only bumped for breaking changes, new fields can be
+added within a version. Consumers that can't follow a new version pin the
+one they understand with webhook_schema_version.
+*/
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// latestWebhookSchemaVersion is sent when webhook_schema_version isn't set
+const latestWebhookSchemaVersion = 2
+
+/*
+webhookPayloadV1 is the original payload, the SavedItem as stored in findings.json,
+with the item's fields under their Go names, plus schema_version.
+*/
+type webhookPayloadV1 struct {
+	SchemaVersion int `json:"schema_version"`
+	SavedItem
+}
+
+/*
+webhookPayloadV2 names the kind of event and uses snake_case item fields
+that don't change with the scraper's internal Item type.
+Event is "new_item", "price_drop" or "shipping_change"; a price drop
+with a shipping change at the same time is sent as "price_drop".
+*/
+type webhookPayloadV2 struct {
+	SchemaVersion    int           `json:"schema_version"`
+	Event            string        `json:"event"`
+	Query            string        `json:"query"`
+	Found            time.Time     `json:"found"`
+	PreviousPrice    *float64      `json:"previous_price,omitempty"`
+	PreviousShipping *float64      `json:"previous_shipping,omitempty"`
+	Item             itemPayloadV2 `json:"item"`
+}
+
+// itemPayloadV2 is the listing in a version 2 payload, Shipping is null if unknown
+type itemPayloadV2 struct {
+	ID             string   `json:"id"`
+	Title          string   `json:"title"`
+	URL            string   `json:"url"`
+	Condition      string   `json:"condition,omitempty"`
+	PriceText      string   `json:"price_text"`
+	Price          float64  `json:"price"`
+	PriceHigh      float64  `json:"price_high"`
+	Currency       string   `json:"currency,omitempty"`
+	Shipping       *float64 `json:"shipping"`
+	ShippingMax    *float64 `json:"shipping_max"`
+	Auction        bool     `json:"auction"`
+	Bids           int      `json:"bids"`
+	Watchers       int      `json:"watchers"`
+	TimeLeft       string   `json:"time_left,omitempty"`
+	SellerName     string   `json:"seller_name,omitempty"`
+	SellerFeedback int      `json:"seller_feedback,omitempty"`
+	SellerRating   float64  `json:"seller_rating,omitempty"`
+	Location       string   `json:"location,omitempty"`
+}
+
+// newWebhookPayloadV2 translates an entry into the version 2 payload
+func newWebhookPayloadV2(saved SavedItem) webhookPayloadV2 {
+	item := saved.Item
+	payload := webhookPayloadV2{
+		SchemaVersion:    2,
+		Event:            "new_item",
+		Query:            saved.QueryTerm,
+		Found:            saved.Found,
+		PreviousShipping: saved.PreviousShipping,
+		Item: itemPayloadV2{
+			ID:             item.ID,
+			Title:          item.Title,
+			URL:            item.URL,
+			Condition:      item.Condition,
+			PriceText:      item.Price,
+			Price:          item.PriceValue,
+			PriceHigh:      item.PriceHigh,
+			Currency:       item.Currency,
+			Auction:        item.IsAuction,
+			Bids:           item.Bids,
+			Watchers:       item.Watchers,
+			TimeLeft:       item.TimeLeft,
+			SellerName:     item.SellerName,
+			SellerFeedback: item.SellerFeedback,
+			SellerRating:   item.SellerRating,
+			Location:       item.ItemLocation,
+		},
+	}
+	if item.ShippingCost >= 0 {
+		shipping, shippingMax := item.ShippingCost, item.ShippingMax
+		payload.Item.Shipping, payload.Item.ShippingMax = &shipping, &shippingMax
+	}
+	switch {
+	case saved.PreviousPrice != 0:
+		previous := saved.PreviousPrice
+		payload.Event, payload.PreviousPrice = "price_drop", &previous
+	case saved.PreviousShipping != nil:
+		payload.Event = "shipping_change"
+	}
+	return payload
+}
+
+// webhookPayload translates an entry into the payload of a schema version, 0 for the latest
+func webhookPayload(saved SavedItem, version int) (any, error) {
+	switch version {
+	case 1:
+		return webhookPayloadV1{SchemaVersion: 1, SavedItem: saved}, nil
+	case 0, 2:
+		return newWebhookPayloadV2(saved), nil
+	}
+	return nil, fmt.Errorf("unknown webhook_schema_version %d, expected 1 to %d", version, latestWebhookSchemaVersion)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWebhookSchemaVersions(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = nil
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("payload is not a JSON object: %v\n%s", err, data)
+		}
+	}))
+	defer server.Close()
+
+	found := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	drop := SavedItem{
+		Item: Item{ID: "1", Title: "Switch OLED", Price: "EUR 229,99", PriceValue: 229.99, PriceHigh: 229.99,
+			Currency: "EUR", ShippingCost: -1, URL: "https://www.ebay.de/itm/1"},
+		Found:         found,
+		QueryTerm:     "switch",
+		PreviousPrice: 249.99,
+	}
+
+	tests := []struct {
+		version int
+		want    map[string]any
+	}{
+		{1, map[string]any{"schema_version": 1.0, "query": "switch", "previous_price": 249.99, "found": "2024-05-01T12:00:00Z"}},
+		{2, map[string]any{"schema_version": 2.0, "event": "price_drop", "query": "switch", "previous_price": 249.99, "found": "2024-05-01T12:00:00Z"}},
+		{0, map[string]any{"schema_version": 2.0, "event": "price_drop"}},
+	}
+	for _, tt := range tests {
+		notifier := &WebhookNotifier{URL: server.URL, Client: server.Client(), SchemaVersion: tt.version}
+		if err := notifier.NotifyChange(drop); err != nil {
+			t.Fatalf("version %d: %v", tt.version, err)
+		}
+		for key, want := range tt.want {
+			if got := body[key]; !reflect.DeepEqual(got, want) {
+				t.Errorf("version %d: %s = %v, want %v", tt.version, key, got, want)
+			}
+		}
+
+		item, _ := body["item"].(map[string]any)
+		switch tt.version {
+		case 1:
+			// The item keeps the Go field names of the original payload
+			if item["Title"] != "Switch OLED" || item["PriceValue"] != 229.99 {
+				t.Errorf("version 1 item = %v", item)
+			}
+		default:
+			if item["title"] != "Switch OLED" || item["price"] != 229.99 || item["id"] != "1" {
+				t.Errorf("version %d item = %v", tt.version, item)
+			}
+			if shipping, ok := item["shipping"]; !ok || shipping != nil {
+				t.Errorf("version %d: unknown shipping = %v, want null", tt.version, shipping)
+			}
+		}
+	}
+
+	// New items are sent as new_item events without previous values
+	notifier := &WebhookNotifier{URL: server.URL, Client: server.Client()}
+	if err := notifier.Notify(Item{ID: "2", Title: "LEGO 42115", ShippingCost: 0}, "lego"); err != nil {
+		t.Fatal(err)
+	}
+	if body["event"] != "new_item" || body["query"] != "lego" {
+		t.Errorf("new item payload = %v", body)
+	}
+	if _, ok := body["previous_price"]; ok {
+		t.Errorf("new item payload has a previous price: %v", body)
+	}
+	if item, _ := body["item"].(map[string]any); item["shipping"] != 0.0 {
+		t.Errorf("free shipping = %v, want 0", item["shipping"])
+	}
+
+	if _, err := webhookPayload(drop, latestWebhookSchemaVersion+1); err == nil {
+		t.Error("webhookPayload accepted an unknown version")
+	}
+}
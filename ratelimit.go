@@ -0,0 +1,50 @@
+/*
+Package main provides a request rate limiter shared by all scrapers.
+It keeps a whole monitoring round, including pagination and retries,
+below a configured number of requests per minute.
+*/
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+RateLimiter spaces requests evenly so no more than a fixed number go out per minute.
+Each caller reserves the next free slot, so concurrent scrapers queue up in turn.
+A nil RateLimiter doesn't limit anything.
+*/
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter creates a limiter allowing perMinute requests per minute, nil if perMinute is not positive
+func NewRateLimiter(perMinute int) *RateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &RateLimiter{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// Wait blocks until the caller may send its next request or ctx is cancelled
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	slot := time.Now()
+	if l.next.After(slot) {
+		slot = l.next
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	if !sleepContext(ctx, time.Until(slot)) {
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+/*
+Package main provides reports aggregating the daily logs over a date range.
+Reports only read existing logs and never contact eBay.
+*/
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+QueryStats aggregates the findings of one query in a report.
+Prices ignore items without a readable price. Price drops and shipping changes
+of known items are only counted in PriceDrops and ShippingChanges, so each item counts once.
+*/
+type QueryStats struct {
+	Query           string
+	Items           int
+	PriceDrops      int
+	ShippingChanges int
+	MinPrice        float64
+	AvgPrice        float64
+	MaxPrice        float64
+	MostWatched     Item
+}
+
+/*
+Report holds the per-query statistics of the daily logs between Start and End.
+*/
+type Report struct {
+	Start   time.Time
+	End     time.Time
+	Days    int // Days with a daily log
+	Queries []QueryStats
+}
+
+// parseDateRange parses a "YYYY-MM-DD:YYYY-MM-DD" range, both days included
+func parseDateRange(value string) (time.Time, time.Time, error) {
+	from, to, ok := strings.Cut(value, ":")
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid range %q, expected YYYY-MM-DD:YYYY-MM-DD", value)
+	}
+	start, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(from), time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date %q, expected YYYY-MM-DD", from)
+	}
+	end, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(to), time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date %q, expected YYYY-MM-DD", to)
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end date %s is before start date %s", to, from)
+	}
+	return start, end, nil
+}
+
+// generateReport reads the daily logs from start to end, both days included,
+// and aggregates them per query
+func generateReport(start, end time.Time) (Report, error) {
+	report := Report{Start: start, End: end}
+	byQuery := make(map[string]*QueryStats)
+	priced := make(map[string]int)
+
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		findings, err := readDailyLog(day)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Days++
+
+		for _, saved := range findings {
+			stats, ok := byQuery[saved.QueryTerm]
+			if !ok {
+				stats = &QueryStats{Query: saved.QueryTerm}
+				byQuery[saved.QueryTerm] = stats
+			}
+			if saved.PreviousPrice != 0 {
+				stats.PriceDrops++
+			}
+			if saved.PreviousShipping != nil {
+				stats.ShippingChanges++
+			}
+			if !saved.isNew() {
+				continue
+			}
+			stats.Items++
+			if stats.Items == 1 || saved.Item.Watchers > stats.MostWatched.Watchers {
+				stats.MostWatched = saved.Item
+			}
+
+			price := saved.Item.PriceValue
+			if price < 0 {
+				continue
+			}
+			if priced[saved.QueryTerm] == 0 || price < stats.MinPrice {
+				stats.MinPrice = price
+			}
+			if price > stats.MaxPrice {
+				stats.MaxPrice = price
+			}
+			// AvgPrice holds the sum until all days are read
+			stats.AvgPrice += price
+			priced[saved.QueryTerm]++
+		}
+	}
+
+	for query, stats := range byQuery {
+		if priced[query] > 0 {
+			stats.AvgPrice /= float64(priced[query])
+		}
+		report.Queries = append(report.Queries, *stats)
+	}
+	sort.Slice(report.Queries, func(i, j int) bool {
+		return report.Queries[i].Query < report.Queries[j].Query
+	})
+	return report, nil
+}
+
+// printReport prints the report as a table with one row per query
+func printReport(report Report) {
+	headerColor.Printf("Findings from %s to %s (%d days with logs)\n\n",
+		report.Start.Format("2006-01-02"), report.End.Format("2006-01-02"), report.Days)
+	if len(report.Queries) == 0 {
+		fmt.Println("No items found in this range")
+		return
+	}
+
+	fmt.Printf("%-30s %7s %7s %8s %10s %10s %10s  %s\n", "Query", "Items", "Drops", "Shipping", "Min", "Avg", "Max", "Most watched")
+	for _, stats := range report.Queries {
+		fmt.Printf("%-30s %7d %7d %8d %10.2f %10.2f %10.2f  %d watchers: %s\n",
+			stats.Query, stats.Items, stats.PriceDrops, stats.ShippingChanges, stats.MinPrice, stats.AvgPrice, stats.MaxPrice,
+			stats.MostWatched.Watchers, stats.MostWatched.Title)
+	}
+}
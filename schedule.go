@@ -0,0 +1,148 @@
+/*
+Package main provides active-window scheduling for the monitor.
+Active windows restrict scraping to configured weekdays and times of day.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+/*
+ActiveWindow is a daily time range during which the monitor scrapes.
+An empty Weekday applies the window to every day. When End is not after
+Start the window runs past midnight into the following day.
+*/
+type ActiveWindow struct {
+	Weekday string `json:"weekday"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+// parseClock converts an "HH:MM" string into a duration since midnight
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// parseWeekday matches english weekday names or their three-letter abbreviations
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+// validate checks that the window's weekday and times can be parsed
+func (w ActiveWindow) validate() error {
+	if w.Weekday != "" {
+		if _, err := parseWeekday(w.Weekday); err != nil {
+			return err
+		}
+	}
+	if _, err := parseClock(w.Start); err != nil {
+		return err
+	}
+	if _, err := parseClock(w.End); err != nil {
+		return err
+	}
+	return nil
+}
+
+// bounds returns the window's start and end on the given day, if it applies to that day
+func (w ActiveWindow) bounds(day time.Time) (time.Time, time.Time, bool) {
+	if w.Weekday != "" {
+		weekday, err := parseWeekday(w.Weekday)
+		if err != nil || weekday != day.Weekday() {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	start, errS := parseClock(w.Start)
+	end, errE := parseClock(w.End)
+	if errS != nil || errE != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	if end <= start {
+		end += 24 * time.Hour
+	}
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	return midnight.Add(start), midnight.Add(end), true
+}
+
+// isActive reports whether now falls inside any of the windows.
+// Without configured windows the monitor is always active.
+func isActive(windows []ActiveWindow, now time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, w := range windows {
+		// Yesterday's window may still be running past midnight
+		for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+			start, end, ok := w.bounds(day)
+			if ok && !now.Before(start) && now.Before(end) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nextWindowStart returns the earliest window start after now
+func nextWindowStart(windows []ActiveWindow, now time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	for offset := 0; offset <= 7; offset++ {
+		day := now.AddDate(0, 0, offset)
+		for _, w := range windows {
+			start, _, ok := w.bounds(day)
+			if ok && start.After(now) && (!found || start.Before(next)) {
+				next = start
+				found = true
+			}
+		}
+	}
+	return next, found
+}
+
+// waitForActiveWindow sleeps until the next active window opens, if currently outside all windows,
+// returning early when ctx is cancelled
+func waitForActiveWindow(ctx context.Context, windows []ActiveWindow) {
+	now := time.Now()
+	if isActive(windows, now) {
+		return
+	}
+	next, ok := nextWindowStart(windows, now)
+	if !ok {
+		return
+	}
+	if jsonLogs {
+		logInfo("", "", "Outside active windows, sleeping until "+next.Format("2006-01-02 15:04"))
+	} else {
+		headerColor.Printf("[%s] Outside active windows, sleeping until %s\n",
+			now.Format("2006-01-02 15:04:05"),
+			next.Format("2006-01-02 15:04"))
+	}
+	sleepContext(ctx, time.Until(next))
+}
+
+// sleepContext pauses for d or until ctx is cancelled, reporting whether the full duration passed
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -5,11 +5,18 @@ It supports both auction and buy-now listings with customizable filters.
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 )
@@ -20,6 +27,10 @@ It can be All, BuyNow, or Auction.
 */
 type ListingType int
 
+// DefaultUserAgent is sent when no user agent is configured. eBay serves
+// stripped-down pages to clients that look like bots.
+const DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
 // Enumeration of listing types supported by the scraper
 const (
 	All     ListingType = iota // All listing types
@@ -27,6 +38,42 @@ const (
 	Auction                    // Auction listings only
 )
 
+/*
+SortOrder selects how eBay orders the search results.
+An empty SortOrder means BestMatch.
+*/
+type SortOrder string
+
+// Sort orders supported by the scraper
+const (
+	BestMatch     SortOrder = "BestMatch"
+	EndingSoonest SortOrder = "EndingSoonest"
+	NewlyListed   SortOrder = "NewlyListed"
+	PriceLowest   SortOrder = "PriceLowest"
+	PriceHighest  SortOrder = "PriceHighest"
+)
+
+// eBay's _sop parameter value for each sort order
+var sortParams = map[SortOrder]int{
+	BestMatch:     12,
+	EndingSoonest: 1,
+	NewlyListed:   10,
+	PriceLowest:   15,
+	PriceHighest:  16,
+}
+
+// sortParam returns the _sop value for a sort order, 0 for the default best match
+func sortParam(order SortOrder) (int, error) {
+	if order == "" || order == BestMatch {
+		return 0, nil
+	}
+	if sop, ok := sortParams[order]; ok {
+		return sop, nil
+	}
+	return 0, fmt.Errorf("unsupported sort order %q, expected one of %s, %s, %s, %s or %s",
+		order, BestMatch, EndingSoonest, NewlyListed, PriceLowest, PriceHighest)
+}
+
 /*
 TimeRange represents a duration with days, hours, and minutes.
 Used for tracking auction time remaining and setting time filters.
@@ -42,10 +89,135 @@ Scraper holds the configuration for filtering eBay listings.
 It maintains criteria for prices, listing types, and time limits.
 */
 type Scraper struct {
+	// Marketplace is the eBay site to search, e.g. "ebay.com"; empty means ebay.de
+	Marketplace string
+	// MaxPages is the number of result pages ScrapeQuery fetches, at least one
+	MaxPages int
+	// MinPageHitRatio stops pagination early once less than this share of a page's
+	// listings pass the filters, e.g. 0.1 for 10%, as deeper best-match pages only get
+	// less relevant. 0 always fetches MaxPages pages.
+	MinPageHitRatio float64
+	// SortOrder is the order eBay returns results in, best match when empty
+	SortOrder SortOrder
+	// CategoryID restricts results to an eBay category via _sacat, 0 searches all
+	// categories. To find it, narrow a search on eBay to the category and copy the
+	// number after "_sacat=" from the URL, e.g. 9355 for cell phones.
+	CategoryID int
+
 	MinPrice    float64
 	MaxPrice    float64
 	ListingType ListingType
 	MaxTimeLeft *TimeRange
+	MinWatchers int
+	MaxWatchers int
+	MinBids     int
+	MaxBids     int
+
+	// ConditionFilter keeps only listings in this condition: "new", "used",
+	// "refurbished" or "any" (default). Listings without a condition label fail it.
+	ConditionFilter string
+
+	// PriceRangeOverlap lets listings with a price range pass when any price in
+	// the range is within MinPrice/MaxPrice instead of comparing the lowest price
+	PriceRangeOverlap bool
+
+	// UseTotalPrice applies MinPrice/MaxPrice to price plus shipping; listings
+	// with unknown shipping are compared by price alone. Shipping ranges count with
+	// their lowest cost, or with UseMaxShipping their highest.
+	UseTotalPrice  bool
+	UseMaxShipping bool
+
+	// FilterLogic combines the enabled filters: "and" (default) requires all
+	// of them to pass, "or" keeps items passing any of them
+	FilterLogic string
+
+	// Phrases matched case-insensitively against the listing subtitle
+	SubtitleContains []string
+	SubtitleExcludes []string
+
+	// Keywords matched case-insensitively against the cleaned title. Unlike the
+	// other filters they always apply, regardless of FilterLogic.
+	ExcludeKeywords []string
+	RequireKeywords []string
+
+	// ExactTokens must each appear in the title as a whole word, e.g. a model number
+	ExactTokens   []string
+	exactTokenRes []*regexp.Regexp // Compiled ExactTokens, set once per search by the monitor
+
+	// RequireVideo keeps only listings that indicate an attached video
+	RequireVideo bool
+	// TopRatedSellersOnly keeps only listings carrying eBay's top-rated seller badge
+	TopRatedSellersOnly bool
+	// EbayPlusOnly keeps only listings with the eBay Plus badge, ignored on
+	// marketplaces without eBay Plus
+	EbayPlusOnly bool
+
+	// OnlyBestOffer keeps only fixed-price listings that accept offers
+	OnlyBestOffer bool
+
+	// ExcludeSponsored drops promoted listings
+	ExcludeSponsored bool
+
+	// ExcludeBundles drops lot/bundle listings, OnlyBundles keeps nothing but them
+	ExcludeBundles bool
+	OnlyBundles    bool
+
+	// MaxQuantityAvailable keeps fixed-price listings with at most this many left, 0 disables it.
+	// Listings that don't show a quantity always pass.
+	MaxQuantityAvailable int
+
+	// MinSellerFeedback keeps listings from sellers with at least this feedback score,
+	// 0 disables it. Listings without seller info always pass.
+	MinSellerFeedback int
+
+	// OnlyFreeReturns keeps listings with free returns. Search result cards often
+	// don't show the return policy, those pass unless StrictReturns is set.
+	OnlyFreeReturns bool
+	StrictReturns   bool
+
+	// LocationFilter keeps listings whose item location contains one of these
+	// phrases (case-insensitive). Listings without a location pass only with
+	// AllowUnknownLocation set.
+	LocationFilter       []string
+	AllowUnknownLocation bool
+
+	// MaxNormalizedPrice keeps listings costing at most this per 100g, 100ml or piece,
+	// 0 disables it. Listings without a recognizable pack size always pass.
+	MaxNormalizedPrice float64
+
+	// MaxPricePerUnit keeps listings whose price divided by the quantity in the title
+	// ("100 Stück", "3er Pack") is at most this, 0 disables it
+	MaxPricePerUnit float64
+	// SortByUnitPrice orders the results of ScrapeQuery by price per unit, cheapest first
+	SortByUnitPrice bool
+
+	// BaseCurrency converts all price values to this currency, e.g. "EUR", using Rates,
+	// so MinPrice/MaxPrice compare listings across marketplaces. Empty keeps the listing's currency.
+	BaseCurrency string
+	Rates        RateSource
+
+	// Selectors locate the parts of the result pages, empty fields use DefaultSelectors
+	Selectors Selectors
+
+	// Cache enables conditional requests when set; nil disables it
+	Cache *ResponseCache
+	// Client is used for all requests, defaulting to a client with a 30s timeout
+	Client *http.Client
+	// UserAgent is sent with every request, DefaultUserAgent when empty
+	UserAgent string
+	// MaxRetries is how often transient failures (network errors, 5xx, 429) are
+	// retried, waiting as long as Backoff says before each retry
+	MaxRetries int
+	Backoff    BackoffStrategy
+	// TraceTimings logs DNS/connect/TLS/server timings for every request attempt,
+	// and records them in the histograms of Metrics if set
+	TraceTimings bool
+	// Metrics counts scrapes and responses when set; nil disables it
+	Metrics *Metrics
+	// Limiter is waited on before every request including retries; nil disables it
+	Limiter *RateLimiter
+	// Concurrency is told about every response to adapt the number of concurrent searches
+	Concurrency *ConcurrencyController
 }
 
 /*
@@ -53,13 +225,48 @@ Item represents a single eBay listing with all relevant information.
 Includes both displayed information and parsed values for filtering.
 */
 type Item struct {
-	Title      string
-	Price      string
-	PriceValue float64
-	URL        string
-	IsAuction  bool
-	Watchers   int
-	TimeLeft   string
+	ID             string // Numeric eBay item id, or the URL if it has none
+	Title          string
+	Subtitle       string
+	Condition      string // Condition label as shown by eBay, e.g. "Gebraucht"
+	Price          string
+	PriceValue     float64 // Lowest price for listings showing a price range
+	PriceHigh      float64 // Highest price of a price range, PriceValue for single prices
+	PriceCents     int64
+	Currency       string  // Currency of the price values, the base currency if converted
+	ShippingCost   float64 // 0 for free shipping, -1 if unknown, the lowest cost of a range
+	ShippingMax    float64 // Highest cost of a shipping range, ShippingCost for a single cost
+	ShippingText   string
+	URL            string
+	IsAuction      bool
+	Watchers       int
+	Bids           int
+	TimeLeft       string
+	HasVideo       bool
+	TopRatedSeller bool
+	EbayPlus       bool
+	AcceptsOffers  bool
+	Sponsored      bool
+	IsBundle       bool
+	BundleQuantity int // Estimated number of items in a bundle, 0 if unknown
+
+	QuantityAvailable int // Remaining quantity for fixed-price listings, 0 if unknown
+
+	ReturnsAccepted bool
+	FreeReturns     bool
+	ReturnsKnown    bool // Whether the card shows a return policy at all
+
+	SellerName     string
+	SellerFeedback int     // Feedback score, 0 if unknown
+	SellerRating   float64 // Positive feedback in percent, 0 if unknown
+	ItemLocation   string  // Location as shown by eBay, e.g. "aus China", empty if not shown
+
+	PackSize        float64 // Total pack size in PackUnit, 0 if unknown
+	PackUnit        string  // "g", "ml" or "pcs"
+	NormalizedPrice float64 // Price per 100g, 100ml or piece, 0 if unknown
+
+	Quantity     int     // Units offered, BundleQuantity or 1 if not stated
+	PricePerUnit float64 // PriceValue divided by Quantity
 }
 
 // NewScraper creates a new scraper instance with default settings
@@ -69,34 +276,141 @@ func NewScraper() *Scraper {
 		MaxPrice:    -1,
 		ListingType: All,
 		MaxTimeLeft: nil,
+		MaxPages:    1,
+		Client:      &http.Client{Timeout: 30 * time.Second},
+		MaxRetries:  2,
+		Backoff:     ExponentialBackoff{Base: 2 * time.Second},
 	}
 }
 
-// parsePrice extracts and normalizes the price from an eBay price string
-func parsePrice(priceStr string) float64 {
-	priceStr = strings.TrimPrefix(priceStr, "EUR")
-	priceStr = strings.TrimSpace(priceStr)
+// Matches the amount of a price including its separators, e.g. "1.234,56" in "EUR 1.234,56"
+var amountRe = regexp.MustCompile(`\d[\d.,]*`)
 
-	priceStr = strings.ReplaceAll(priceStr, ",", ".")
+// parsePrice extracts and normalizes the price from an eBay price string.
+// The decimal separator is told apart from the thousands separator by position,
+// see decimalSeparator, so "$1,234.56" on an English site and "EUR 12.50" on a
+// site writing "1.234,56" are both read correctly.
+func parsePrice(priceStr string, decimalComma bool) float64 {
+	// Price ranges like "EUR 10,00 bis EUR 20,00" count from their lower bound
+	priceStr = priceRangeRe.Split(priceStr, 2)[0]
+	amount := strings.TrimRight(amountRe.FindString(priceStr), ".,")
+	if amount == "" {
+		return -1
+	}
 
-	re := regexp.MustCompile(`[^0-9.]`)
-	cleanPrice := re.ReplaceAllString(priceStr, "")
+	decimal := decimalSeparator(amount, decimalComma)
+	var clean strings.Builder
+	for _, r := range amount {
+		switch {
+		case r >= '0' && r <= '9':
+			clean.WriteRune(r)
+		case r == decimal:
+			clean.WriteByte('.')
+		}
+	}
 
-	price, err := strconv.ParseFloat(cleanPrice, 64)
+	price, err := strconv.ParseFloat(clean.String(), 64)
 	if err != nil {
 		return -1
 	}
 	return price
 }
 
+// decimalSeparator returns the rune separating the decimals of an amount, 0 if it has none.
+// With both "." and "," the last one separates the decimals, as in "1.234,56" and "1,234.56".
+// A separator appearing several times groups thousands, as in "1.234.567". A single
+// separator followed by one or two digits separates the decimals, as in "12.50" or "12,5".
+// Followed by three digits it is taken as the site's thousands separator, "." with
+// decimalComma and "," otherwise, and as the decimal separator if it's the other one.
+func decimalSeparator(amount string, decimalComma bool) rune {
+	lastDot, lastComma := strings.LastIndex(amount, "."), strings.LastIndex(amount, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastDot > lastComma {
+			return '.'
+		}
+		return ','
+	case lastDot < 0 && lastComma < 0:
+		return 0
+	}
+
+	separator, last := '.', lastDot
+	if lastComma >= 0 {
+		separator, last = ',', lastComma
+	}
+	if strings.Count(amount, string(separator)) > 1 {
+		return 0
+	}
+	if digits := len(amount) - last - 1; digits != 3 {
+		return separator
+	}
+	thousands := ','
+	if decimalComma {
+		thousands = '.'
+	}
+	if separator == thousands {
+		return 0
+	}
+	return separator
+}
+
+// parseShipping extracts the shipping cost from strings like "+EUR 4,99 Versand",
+// returning 0 for free shipping and -1 if no cost can be found. For ranges such
+// as "EUR 4,99 bis EUR 6,99" the lower bound is used.
+func parseShipping(shippingStr string, decimalComma bool) float64 {
+	lower := strings.ToLower(shippingStr)
+	if strings.Contains(lower, "kostenlos") || strings.Contains(lower, "gratis") || strings.Contains(lower, "free") {
+		return 0
+	}
+
+	re := regexp.MustCompile(`\d[\d.,]*`)
+	amount := strings.TrimRight(re.FindString(shippingStr), ".,")
+	if amount == "" {
+		return -1
+	}
+	return parsePrice(amount, decimalComma)
+}
+
+// parseShippingMax returns the upper bound of a shipping range such as
+// "+EUR 4,99 bis EUR 6,99 Versand", or the same cost as parseShipping otherwise
+func parseShippingMax(shippingStr string, decimalComma bool) float64 {
+	low := parseShipping(shippingStr, decimalComma)
+	bounds := priceRangeRe.Split(shippingStr, 2)
+	// "shipping to Germany" splits as well, but has no cost after the separator
+	if high := parseShipping(bounds[len(bounds)-1], decimalComma); len(bounds) == 2 && high > low {
+		return high
+	}
+	return low
+}
+
+// Separates the bounds of a price range, e.g. "EUR 10,00 bis EUR 20,00" or "$10.00 to $20.00"
+var priceRangeRe = regexp.MustCompile(`(?i)\s+(?:bis|to)\s+`)
+
+// parsePriceHigh returns the upper bound of a price range, or the price itself for single prices
+func parsePriceHigh(priceStr string, decimalComma bool) float64 {
+	bounds := priceRangeRe.Split(priceStr, 2)
+	return parsePrice(bounds[len(bounds)-1], decimalComma)
+}
+
+// toCents converts a price into whole cents, keeping negative values as "unknown"
+func toCents(price float64) int64 {
+	if price < 0 {
+		return -1
+	}
+	return int64(math.Round(price * 100))
+}
+
 // cleanTitle removes common prefixes and normalizes the listing title
 func cleanTitle(title string) string {
 	title = strings.TrimPrefix(title, "Neues Angebot")
+	title = strings.TrimPrefix(title, "New Listing")
 	title = strings.TrimSpace(title)
 	return title
 }
 
-// isValidItem checks if a listing has all required fields and is not a promotional item
+// isValidItem checks if a listing has all required fields and is not a promotional item.
+// Placeholders like "Shop on eBay" and itmmeta links aren't listings at all, sponsored
+// listings are real ones and are flagged by isSponsored instead.
 func isValidItem(title, price, url string) bool {
 	if title == "" || price == "" || url == "" {
 		return false
@@ -110,28 +424,124 @@ func isValidItem(title, price, url string) bool {
 	return true
 }
 
-// isInPriceRange checks if an item's price falls within the configured range
-func (s *Scraper) isInPriceRange(price float64) bool {
-	if price < 0 {
+// filterPriceCents returns the price in cents the price range is applied to,
+// adding the lowest or, with UseMaxShipping, the highest shipping cost to priceCents if configured
+func (s *Scraper) filterPriceCents(item Item, priceCents int64) int64 {
+	shipping := item.ShippingCost
+	if s.UseMaxShipping && item.ShippingMax > shipping {
+		shipping = item.ShippingMax
+	}
+	if s.UseTotalPrice && priceCents >= 0 && shipping >= 0 {
+		return priceCents + toCents(shipping)
+	}
+	return priceCents
+}
+
+// matchesPriceRange checks an item's price against MinPrice and MaxPrice. Listings showing
+// a price range are compared by their lowest price, or with PriceRangeOverlap pass
+// if any price within their range fits.
+func (s *Scraper) matchesPriceRange(item Item) bool {
+	low := s.filterPriceCents(item, item.PriceCents)
+	highCents := toCents(item.PriceHigh)
+	if !s.PriceRangeOverlap || highCents <= item.PriceCents {
+		return s.isInPriceRange(low)
+	}
+
+	high := s.filterPriceCents(item, highCents)
+	if low < 0 {
+		return false
+	}
+	if s.MinPrice >= 0 && high < toCents(s.MinPrice) {
 		return false
 	}
-	if s.MinPrice >= 0 && price < s.MinPrice {
+	if s.MaxPrice >= 0 && low > toCents(s.MaxPrice) {
 		return false
 	}
-	if s.MaxPrice >= 0 && price > s.MaxPrice {
+	return true
+}
+
+// isInPriceRange checks if an item's price in cents falls within the configured range
+func (s *Scraper) isInPriceRange(priceCents int64) bool {
+	if priceCents < 0 {
+		return false
+	}
+	if s.MinPrice >= 0 && priceCents < toCents(s.MinPrice) {
+		return false
+	}
+	if s.MaxPrice >= 0 && priceCents > toCents(s.MaxPrice) {
 		return false
 	}
 	return true
 }
 
 // isAuction determines if a listing is an auction based on eBay's HTML structure
-func isAuction(selection *goquery.Selection) bool {
+func isAuction(selection *goquery.Selection, sel Selectors) bool {
 	// Check for auction-specific elements
-	timeLeft := selection.Find(".s-item__time-left").Text()
-	bids := selection.Find(".s-item__bids").Text()
+	timeLeft := selection.Find(sel.TimeLeft).Text()
+	bids := selection.Find(sel.Bids).Text()
 	return timeLeft != "" || bids != ""
 }
 
+// hasVideo determines if a listing card indicates an attached video
+func hasVideo(selection *goquery.Selection, sel Selectors) bool {
+	return selection.Find(sel.Video).Length() > 0
+}
+
+// isTopRatedSeller checks for eBay's top-rated seller badge on a listing card
+func isTopRatedSeller(selection *goquery.Selection, sel Selectors) bool {
+	if selection.Find(sel.TopRatedBadge).Length() > 0 {
+		return true
+	}
+	// Only the seller details, a title like "TOPSELLER Nintendo Switch" says nothing about the seller
+	text := strings.ToLower(selection.Find(sel.SellerInfo).Text())
+	return strings.Contains(text, "top-bewerteter verkäufer") ||
+		strings.Contains(text, "topseller") ||
+		strings.Contains(text, "top rated plus")
+}
+
+// acceptsOffers checks for the Best Offer hint ("Preisvorschlag", "or Best Offer") on a listing card
+func acceptsOffers(selection *goquery.Selection, sel Selectors) bool {
+	if selection.Find(sel.BestOffer).Length() > 0 {
+		return true
+	}
+	text := strings.ToLower(selection.Find(sel.PurchaseOptions).Text())
+	return strings.Contains(text, "preisvorschlag") || strings.Contains(text, "best offer")
+}
+
+// Matches eBay's label on promoted listings in German and English
+var sponsoredRe = regexp.MustCompile(`(?i)\b(?:anzeige|gesponsert|sponsored)\b`)
+
+// isSponsored checks for the promoted listing label ("Anzeige", "Sponsored") on a listing card,
+// shown next to the separator or title, or only as an accessibility label
+func isSponsored(selection *goquery.Selection, sel Selectors) bool {
+	if sponsoredRe.MatchString(selection.Find(sel.SponsoredLabel).Text()) {
+		return true
+	}
+	sponsored := false
+	selection.Find("[aria-label]").EachWithBreak(func(i int, labelled *goquery.Selection) bool {
+		label, _ := labelled.Attr("aria-label")
+		sponsored = sponsoredRe.MatchString(label)
+		return !sponsored
+	})
+	return sponsored
+}
+
+// isEbayPlus checks for the eBay Plus badge on a listing card
+func isEbayPlus(selection *goquery.Selection, sel Selectors) bool {
+	if selection.Find(sel.EbayPlusBadge).Length() > 0 {
+		return true
+	}
+	return strings.Contains(strings.ToLower(cardDetailsText(selection, sel)), "ebay plus")
+}
+
+// cardDetailsText returns the text of a listing card without its title and subtitle,
+// which are written by the seller and can contain anything, e.g. "wie eBay Plus"
+func cardDetailsText(selection *goquery.Selection, sel Selectors) string {
+	details := selection.Clone()
+	details.Find(sel.Title + ", " + sel.Subtitle).Remove()
+	return details.Text()
+}
+
 // shouldIncludeItem verifies if an item matches the configured listing type
 func (s *Scraper) shouldIncludeItem(item Item) bool {
 	switch s.ListingType {
@@ -147,6 +557,318 @@ func (s *Scraper) shouldIncludeItem(item Item) bool {
 	return true
 }
 
+// isInWatcherRange checks if an item's watcher count falls within the configured range
+func (s *Scraper) isInWatcherRange(watchers int) bool {
+	if s.MinWatchers > 0 && watchers < s.MinWatchers {
+		return false
+	}
+	if s.MaxWatchers > 0 && watchers > s.MaxWatchers {
+		return false
+	}
+	return true
+}
+
+// isInBidRange checks if an item's bid count falls within the configured range
+func (s *Scraper) isInBidRange(bids int) bool {
+	if s.MinBids > 0 && bids < s.MinBids {
+		return false
+	}
+	if s.MaxBids > 0 && bids > s.MaxBids {
+		return false
+	}
+	return true
+}
+
+// matchesCondition checks if an item's condition matches the configured filter
+func (s *Scraper) matchesCondition(condition string) bool {
+	filter := strings.ToLower(s.ConditionFilter)
+	if filter == "" || filter == "any" {
+		return true
+	}
+	return conditionCategory(condition) == filter
+}
+
+// matchesSubtitle checks the subtitle against the required and excluded phrases
+func (s *Scraper) matchesSubtitle(subtitle string) bool {
+	subtitle = strings.ToLower(subtitle)
+	for _, phrase := range s.SubtitleExcludes {
+		if strings.Contains(subtitle, strings.ToLower(phrase)) {
+			return false
+		}
+	}
+	for _, phrase := range s.SubtitleContains {
+		if !strings.Contains(subtitle, strings.ToLower(phrase)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesKeywords checks the title against the excluded and required keywords, ignoring case
+func (s *Scraper) matchesKeywords(title string) bool {
+	title = strings.ToLower(title)
+	for _, keyword := range s.ExcludeKeywords {
+		if strings.Contains(title, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	for _, keyword := range s.RequireKeywords {
+		if !strings.Contains(title, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenRegexp compiles a pattern matching token as a whole word, ignoring case.
+// Letters and digits on either side of a match count as part of the same word,
+// so "A1706" does not match inside "A17060".
+func tokenRegexp(token string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(^|[^\p{L}\p{N}])` + regexp.QuoteMeta(strings.TrimSpace(token)) + `($|[^\p{L}\p{N}])`)
+}
+
+// compileExactTokens compiles the patterns of exact tokens, skipping blank ones
+func compileExactTokens(tokens []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(tokens))
+	for _, token := range tokens {
+		if strings.TrimSpace(token) != "" {
+			patterns = append(patterns, tokenRegexp(token))
+		}
+	}
+	return patterns
+}
+
+// exactTokenPatterns returns the compiled ExactTokens. The monitor compiles them once per
+// search and hands them to each round's scraper, other scrapers compile them on first use.
+func (s *Scraper) exactTokenPatterns() []*regexp.Regexp {
+	if s.exactTokenRes == nil {
+		s.exactTokenRes = compileExactTokens(s.ExactTokens)
+	}
+	return s.exactTokenRes
+}
+
+// matchesExactTokens checks that every exact token appears in the title
+func (s *Scraper) matchesExactTokens(title string) bool {
+	for _, re := range s.exactTokenPatterns() {
+		if !re.MatchString(title) {
+			return false
+		}
+	}
+	return true
+}
+
+// Phrases that mark a listing as a lot or bundle of several items
+var bundleKeywordsRe = regexp.MustCompile(`(?i)(?:^|[^\pL])(?:konvolut|sammlung|lot of|job lot|bundle|paket)(?:$|[^\pL])`)
+
+// Dimensions such as "27 x 15" or "30x40cm", removed before looking for quantities.
+// Multi-packs like "3 x 500g" are kept, see packWeightRe.
+var dimensionsRe = regexp.MustCompile(`(?i)\d+(?:[.,]\d+)?\s*[x×]\s*\d+(?:[.,]\d+)?(?:\s*(?:kg|gramm|g|ml|liter|l)\b)?`)
+
+// Matches a weight or volume unit at the end of a dimensions match
+var packWeightRe = regexp.MustCompile(`(?i)\d\s*(?:kg|gramm|g|ml|liter|l)$`)
+
+// Patterns capturing the number of units in a lot, bundle or multi-pack, e.g. "lot of 10",
+// "3er Pack", "3 x 500g", "x10", "10x Spiele" or "100 Stück". A trailing "x" needs a
+// following word, so model names like "3DS XL 2 x" aren't read as quantities.
+var bundleQuantityPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(?:lot of|set of|pack of|konvolut(?: aus| von| mit)?)\s*(\d+)`),
+	regexp.MustCompile(`(?i)\b(\d+)er[- ]?(?:pack|packung|set)\b`),
+	regexp.MustCompile(`(?i)(?:^|\s)(\d+)\s?[x×]\s*\d+(?:[.,]\d+)?\s*(?:kg|gramm|g|ml|liter|l)\b`),
+	regexp.MustCompile(`(?i)(?:^|\s)x\s?(\d+)(?:$|[^\pL\d.,])`),
+	regexp.MustCompile(`(?i)(?:^|\s)(\d+)\s?x\s+\pL`),
+	regexp.MustCompile(`(?i)\b(\d+)[- ]?(?:stück|stk|pack|pcs|pieces)(?:$|[^\pL])`),
+}
+
+// parseQuantity extracts how many units a listing's title offers, 1 if no quantity is found
+func parseQuantity(title string) int {
+	text := dimensionsRe.ReplaceAllStringFunc(title, func(match string) string {
+		if packWeightRe.MatchString(match) {
+			return match
+		}
+		return " "
+	})
+	for _, re := range bundleQuantityPatterns {
+		if matches := re.FindStringSubmatch(text); len(matches) > 1 {
+			if count, err := strconv.Atoi(matches[1]); err == nil && count > 1 {
+				return count
+			}
+		}
+	}
+	return 1
+}
+
+// parseBundle detects lot/bundle listings from the title and subtitle and
+// estimates how many items they contain
+func parseBundle(title, subtitle string) (bool, int) {
+	text := title + " " + subtitle
+	if quantity := parseQuantity(text); quantity > 1 {
+		return true, quantity
+	}
+	return bundleKeywordsRe.MatchString(dimensionsRe.ReplaceAllString(text, " ")), 0
+}
+
+// Matches stock hints such as "Nur noch 2 verfügbar", "3 verfügbar" or "Only 2 left"
+var quantityAvailableRe = regexp.MustCompile(`(?i)(?:nur noch|only)\s+(\d+)\s+(?:verfügbar|left|available)|(\d+)\s+verfügbar`)
+
+// parseQuantityAvailable extracts the remaining quantity from a listing card's details text,
+// as the title and subtitle can mention quantities that aren't the stock
+func parseQuantityAvailable(text string) int {
+	matches := quantityAvailableRe.FindStringSubmatch(text)
+	if matches == nil {
+		return 0
+	}
+	for _, match := range matches[1:] {
+		if count, err := strconv.Atoi(match); err == nil {
+			return count
+		}
+	}
+	return 0
+}
+
+// isInQuantityRange checks if a listing's remaining quantity is within the configured maximum
+func (s *Scraper) isInQuantityRange(quantity int) bool {
+	if s.MaxQuantityAvailable <= 0 || quantity == 0 {
+		return true
+	}
+	return quantity <= s.MaxQuantityAvailable
+}
+
+// Return policy hints on a listing card, checked in this order
+var (
+	noReturnsRe       = regexp.MustCompile(`(?i)keine rücknahme|rücknahme (?:ausgeschlossen|nicht möglich)|no returns|returns not accepted`)
+	freeReturnsRe     = regexp.MustCompile(`(?i)kostenlose rücknahme|kostenloser rückversand|free returns`)
+	returnsAcceptedRe = regexp.MustCompile(`(?i)\d+\s*tage\s+rücknahme|rücknahme\s+(?:möglich|akzeptiert)|returns accepted|\d+[- ]days? returns`)
+)
+
+// parseReturns reads the return policy from a listing card's details text, reporting whether
+// returns are accepted, free, and whether the card shows a policy at all
+func parseReturns(text string) (accepted, free, known bool) {
+	switch {
+	case noReturnsRe.MatchString(text):
+		return false, false, true
+	case freeReturnsRe.MatchString(text):
+		return true, true, true
+	case returnsAcceptedRe.MatchString(text):
+		return true, false, true
+	}
+	return false, false, false
+}
+
+// matchesReturns checks the OnlyFreeReturns filter. Listings that don't show a
+// return policy pass unless StrictReturns is set.
+func (s *Scraper) matchesReturns(item Item) bool {
+	if !item.ReturnsKnown {
+		return !s.StrictReturns
+	}
+	return item.FreeReturns
+}
+
+// Matches seller info such as "sellername (1.234) 99,5%" or "sellername (1,234) 99.5%"
+var sellerInfoRe = regexp.MustCompile(`^\s*(.+?)\s*\((\d[\d.,]*)\)\s*(\d+(?:[.,]\d+)?)\s*%`)
+
+// parseSellerInfo extracts the seller name, feedback score and positive rating in percent,
+// returning empty/zero values when the seller block is absent or unrecognized
+func parseSellerInfo(text string, decimalComma bool) (string, int, float64) {
+	matches := sellerInfoRe.FindStringSubmatch(text)
+	if matches == nil {
+		return "", 0, 0
+	}
+	feedback, err := strconv.Atoi(strings.NewReplacer(".", "", ",", "").Replace(matches[2]))
+	if err != nil {
+		return "", 0, 0
+	}
+	rating := parsePrice(matches[3], decimalComma)
+	if rating < 0 {
+		rating = 0
+	}
+	return matches[1], feedback, rating
+}
+
+// meetsSellerFeedback checks the seller's feedback score against the configured minimum.
+// Listings without seller info always pass.
+func (s *Scraper) meetsSellerFeedback(item Item) bool {
+	if s.MinSellerFeedback <= 0 || item.SellerName == "" {
+		return true
+	}
+	return item.SellerFeedback >= s.MinSellerFeedback
+}
+
+// matchesLocation checks the item location against the allowed phrases.
+// Listings without a location pass only when AllowUnknownLocation is set.
+func (s *Scraper) matchesLocation(location string) bool {
+	if location == "" {
+		return s.AllowUnknownLocation
+	}
+	location = strings.ToLower(location)
+	for _, allowed := range s.LocationFilter {
+		if strings.Contains(location, strings.ToLower(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches pack sizes such as "500g", "1,5 kg", "3 x 250ml" or "120 Kapseln"
+var packSizeRe = regexp.MustCompile(`(?i)(?:(\d+)\s*x\s*)?(\d+(?:[.,]\d+)?)\s*(kg|g|gramm|ml|l|liter|kapseln|tabletten|caps|capsules|tablets)(?:$|[^\p{L}])`)
+
+// parsePackSize extracts the total pack size from the title or subtitle, converted
+// to grams, milliliters or pieces
+func parsePackSize(text string) (float64, string) {
+	matches := packSizeRe.FindStringSubmatch(text)
+	if matches == nil {
+		return 0, ""
+	}
+	size, err := strconv.ParseFloat(strings.ReplaceAll(matches[2], ",", "."), 64)
+	if err != nil || size <= 0 {
+		return 0, ""
+	}
+	if matches[1] != "" {
+		if packs, err := strconv.Atoi(matches[1]); err == nil && packs > 0 {
+			size *= float64(packs)
+		}
+	}
+
+	switch strings.ToLower(matches[3]) {
+	case "kg":
+		return size * 1000, "g"
+	case "g", "gramm":
+		return size, "g"
+	case "l", "liter":
+		return size * 1000, "ml"
+	case "ml":
+		return size, "ml"
+	default:
+		return size, "pcs"
+	}
+}
+
+// normalizedPrice returns the price per 100g, 100ml or piece for a pack
+func normalizedPrice(price float64, size float64, unit string) float64 {
+	if price < 0 || size <= 0 {
+		return 0
+	}
+	if unit == "pcs" {
+		return price / size
+	}
+	return price / size * 100
+}
+
+// isInPricePerUnitRange checks the price per unit against the configured maximum
+func (s *Scraper) isInPricePerUnitRange(perUnit float64) bool {
+	if s.MaxPricePerUnit <= 0 {
+		return true
+	}
+	return perUnit <= s.MaxPricePerUnit
+}
+
+// isInNormalizedPriceRange checks the normalized price against the configured maximum
+func (s *Scraper) isInNormalizedPriceRange(normalized float64) bool {
+	if s.MaxNormalizedPrice <= 0 || normalized == 0 {
+		return true
+	}
+	return normalized <= s.MaxNormalizedPrice
+}
+
 // parseWatchers extracts the number of watchers from eBay's watcher text
 func parseWatchers(watcherStr string) int {
 	// Extract number from strings like "12 watchers"
@@ -161,45 +883,99 @@ func parseWatchers(watcherStr string) int {
 	return 0
 }
 
-// parseTimeLeft converts eBay's time remaining text into a structured TimeRange
-func parseTimeLeft(timeStr string) *TimeRange {
-	if timeStr == "" {
-		return nil
+// parseBids extracts the bid count from strings like "7 Gebote" or "7 bids"
+func parseBids(bidStr string) int {
+	re := regexp.MustCompile(`(\d+)`)
+	matches := re.FindStringSubmatch(bidStr)
+	if len(matches) > 1 {
+		count, err := strconv.Atoi(matches[1])
+		if err == nil {
+			return count
+		}
 	}
+	return 0
+}
 
-	// Extract days, hours, and minutes
-	daysRe := regexp.MustCompile(`(\d+)T`)      // Match "5T" format
-	hoursRe := regexp.MustCompile(`(\d+)Std`)   // Match "12Std" format
-	minsRe := regexp.MustCompile(`(\d+)\s*Min`) // Match "30 Min" format
-
-	days := 0
-	hours := 0
-	mins := 0
-
-	if matches := daysRe.FindStringSubmatch(timeStr); len(matches) > 1 {
-		days, _ = strconv.Atoi(matches[1])
+// conditionCategory maps a German or English condition label to "new", "used"
+// or "refurbished", returning "" for unknown labels
+func conditionCategory(condition string) string {
+	lower := strings.ToLower(strings.TrimSpace(condition))
+	switch {
+	case lower == "":
+		return ""
+	case strings.Contains(lower, "refurbished") || strings.Contains(lower, "überholt"):
+		return "refurbished"
+	case strings.Contains(lower, "gebraucht") || strings.Contains(lower, "used") ||
+		strings.Contains(lower, "pre-owned") || strings.Contains(lower, "neuwertig") ||
+		strings.Contains(lower, "wie neu") || strings.Contains(lower, "like new"):
+		return "used"
+	case strings.Contains(lower, "neu") || strings.Contains(lower, "new"):
+		return "new"
 	}
-	if matches := hoursRe.FindStringSubmatch(timeStr); len(matches) > 1 {
-		hours, _ = strconv.Atoi(matches[1])
+	return ""
+}
+
+// validConditionFilter reports whether filter is a supported ConditionFilter value
+func validConditionFilter(filter string) bool {
+	switch strings.ToLower(filter) {
+	case "", "any", "new", "used", "refurbished":
+		return true
 	}
-	if matches := minsRe.FindStringSubmatch(timeStr); len(matches) > 1 {
-		mins, _ = strconv.Atoi(matches[1])
+	return false
+}
+
+// Units of German remaining times such as "5T 12Std", "Noch 1 Tag 3 Std" or "1T 0Std 5 Min"
+var (
+	germanDaysRe    = regexp.MustCompile(`(?i)(?:^|[^\pL\d])(\d+)\s*(?:tagen|tage|tag|t)(?:[^\pL]|$)`)
+	germanHoursRe   = regexp.MustCompile(`(?i)(?:^|[^\pL\d])(\d+)\s*(?:stunden|stunde|std)(?:[^\pL]|$)`)
+	germanMinutesRe = regexp.MustCompile(`(?i)(?:^|[^\pL\d])(\d+)\s*(?:minuten|minute|min)(?:[^\pL]|$)`)
+	germanSecondsRe = regexp.MustCompile(`(?i)(?:^|[^\pL\d])(\d+)\s*(?:sekunden|sek)(?:[^\pL]|$)`)
+)
+
+// Units of English remaining times such as "5d 12h left" or "2 days 3 hours"
+var (
+	englishDaysRe    = regexp.MustCompile(`(?i)(\d+)\s*(?:days?|d)\b`)
+	englishHoursRe   = regexp.MustCompile(`(?i)(\d+)\s*(?:hours?|hrs?|h)\b`)
+	englishMinutesRe = regexp.MustCompile(`(?i)(\d+)\s*(?:minutes?|mins?|m)\b`)
+	englishSecondsRe = regexp.MustCompile(`(?i)(\d+)\s*(?:seconds?|secs?|s)\b`)
+)
+
+// parseTimeLeft converts eBay's time remaining text into a structured TimeRange.
+// It returns nil if the text contains no recognizable time unit.
+func parseTimeLeft(timeStr string, german bool) *TimeRange {
+	if german {
+		return parseTimeUnits(timeStr, germanDaysRe, germanHoursRe, germanMinutesRe, germanSecondsRe)
 	}
+	return parseTimeUnits(timeStr, englishDaysRe, englishHoursRe, englishMinutesRe, englishSecondsRe)
+}
 
-	// Handle "Noch XTage YStd" format
-	if days == 0 && hours == 0 && mins == 0 {
-		parts := strings.Fields(timeStr)
-		for i, part := range parts {
-			if strings.HasPrefix(part, "T") && i > 0 {
-				days, _ = strconv.Atoi(parts[i-1])
-			}
-			if strings.HasPrefix(part, "Std") && i > 0 {
-				hours, _ = strconv.Atoi(parts[i-1])
-			}
+// parseTimeUnits extracts days, hours and minutes using one pattern per unit.
+// Seconds only count as a recognized unit, e.g. for "30 Sek", and round down to 0 minutes.
+func parseTimeUnits(timeStr string, daysRe, hoursRe, minsRe, secsRe *regexp.Regexp) *TimeRange {
+	found := false
+	unit := func(re *regexp.Regexp) int {
+		matches := re.FindStringSubmatch(timeStr)
+		if len(matches) < 2 {
+			return 0
+		}
+		value, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return 0
 		}
+		found = true
+		return value
 	}
 
-	return &TimeRange{Days: days, Hours: hours, Minutes: mins}
+	timeRange := &TimeRange{
+		Days:    unit(daysRe),
+		Hours:   unit(hoursRe),
+		Minutes: unit(minsRe),
+	}
+	unit(secsRe)
+	if !found {
+		return nil
+	}
+	return timeRange
 }
 
 // toMinutes converts a TimeRange into total minutes for comparison
@@ -207,6 +983,53 @@ func (tr *TimeRange) toMinutes() int {
 	return (tr.Days * 24 * 60) + (tr.Hours * 60) + tr.Minutes
 }
 
+// String formats the time range as "DD:HH:MM"
+func (tr TimeRange) String() string {
+	return fmt.Sprintf("%02d:%02d:%02d", tr.Days, tr.Hours, tr.Minutes)
+}
+
+// parseTimeRange parses a time range in the "DD:HH:MM" format
+func parseTimeRange(value string) (*TimeRange, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) == 3 {
+		days, errD := strconv.Atoi(parts[0])
+		hours, errH := strconv.Atoi(parts[1])
+		minutes, errM := strconv.Atoi(parts[2])
+
+		if errD == nil && errH == nil && errM == nil &&
+			days >= 0 && hours >= 0 && hours < 24 &&
+			minutes >= 0 && minutes < 60 {
+			return &TimeRange{
+				Days:    days,
+				Hours:   hours,
+				Minutes: minutes,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid time range %q, expected DD:HH:MM", value)
+}
+
+// MarshalJSON writes the time range as a "DD:HH:MM" string
+func (tr *TimeRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tr.String())
+}
+
+// UnmarshalJSON reads a "DD:HH:MM" string or the older {"days", "hours", "minutes"} object
+func (tr *TimeRange) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		// timeRangeFields has no methods, so this decodes the plain struct
+		type timeRangeFields TimeRange
+		return json.Unmarshal(data, (*timeRangeFields)(tr))
+	}
+	parsed, err := parseTimeRange(value)
+	if err != nil {
+		return err
+	}
+	*tr = *parsed
+	return nil
+}
+
 // isInTimeRange checks if an item's remaining time is within configured limits
 func (s *Scraper) isInTimeRange(timeLeft *TimeRange) bool {
 	if s.MaxTimeLeft == nil {
@@ -227,60 +1050,471 @@ func (s *Scraper) shouldCheckTime() bool {
 	return s.ListingType == Auction && s.MaxTimeLeft != nil
 }
 
-// Scrape performs the actual web scraping of eBay search results
-func (s *Scraper) Scrape(url string) ([]Item, error) {
-	resp, err := http.Get(url)
+// filterCheck is the outcome of a single filter for an item
+type filterCheck struct {
+	enabled bool
+	passed  bool
+}
+
+// filterChecks evaluates every filter against an item, noting which ones are configured
+func (s *Scraper) filterChecks(item Item, timeRange *TimeRange) []filterCheck {
+	marketplace, _ := s.marketplace()
+	return []filterCheck{
+		{s.MinPrice >= 0 || s.MaxPrice >= 0, s.matchesPriceRange(item)},
+		{s.ListingType != All, s.shouldIncludeItem(item)},
+		{s.MinWatchers > 0 || s.MaxWatchers > 0, s.isInWatcherRange(item.Watchers)},
+		{s.MinBids > 0 || s.MaxBids > 0, s.isInBidRange(item.Bids)},
+		{s.ConditionFilter != "" && !strings.EqualFold(s.ConditionFilter, "any"), s.matchesCondition(item.Condition)},
+		{s.MaxTimeLeft != nil, s.isInTimeRange(timeRange)},
+		{len(s.SubtitleContains) > 0 || len(s.SubtitleExcludes) > 0, s.matchesSubtitle(item.Subtitle)},
+		{len(s.ExactTokens) > 0, s.matchesExactTokens(item.Title)},
+		{s.RequireVideo, item.HasVideo},
+		{s.TopRatedSellersOnly, item.TopRatedSeller},
+		{s.EbayPlusOnly && marketplace.EbayPlus, item.EbayPlus},
+		{s.OnlyBestOffer, item.AcceptsOffers},
+		{s.ExcludeSponsored, !item.Sponsored},
+		{s.ExcludeBundles, !item.IsBundle},
+		{s.OnlyBundles, item.IsBundle},
+		{s.MaxQuantityAvailable > 0, s.isInQuantityRange(item.QuantityAvailable)},
+		{s.MaxNormalizedPrice > 0, s.isInNormalizedPriceRange(item.NormalizedPrice)},
+		{s.MaxPricePerUnit > 0, s.isInPricePerUnitRange(item.PricePerUnit)},
+		{s.MinSellerFeedback > 0, s.meetsSellerFeedback(item)},
+		{len(s.LocationFilter) > 0, s.matchesLocation(item.ItemLocation)},
+		{s.OnlyFreeReturns, s.matchesReturns(item)},
+	}
+}
+
+// matchesFilters combines the enabled filters according to FilterLogic.
+// With no filters enabled every item matches.
+func (s *Scraper) matchesFilters(item Item, timeRange *TimeRange) bool {
+	checks := s.filterChecks(item, timeRange)
+
+	if !strings.EqualFold(s.FilterLogic, "or") {
+		for _, check := range checks {
+			if check.enabled && !check.passed {
+				return false
+			}
+		}
+		return true
+	}
+
+	anyEnabled := false
+	for _, check := range checks {
+		if check.enabled {
+			anyEnabled = true
+			if check.passed {
+				return true
+			}
+		}
+	}
+	return !anyEnabled
+}
+
+// newRequest builds a GET request with browser-like headers for the marketplace,
+// cancelled together with ctx
+func (s *Scraper) newRequest(ctx context.Context, url string, marketplace Marketplace) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
+
+	userAgent := s.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	if marketplace.AcceptLanguage != "" {
+		req.Header.Set("Accept-Language", marketplace.AcceptLanguage)
+	}
+	return req, nil
+}
+
+// isRetryableStatus reports whether a status code indicates a transient failure
+func isRetryableStatus(code int) bool {
+	return code >= 500 || code == http.StatusTooManyRequests
+}
+
+// do sends a request, retrying transient failures including 429 responses after the
+// delay of the backoff strategy. Other error statuses such as 404 are returned
+// immediately, as is the error of the request's context once it is cancelled.
+func (s *Scraper) do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := s.Limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		// Each attempt gets its own trace, so a retry doesn't overwrite the timings of the previous one
+		attemptReq := req.Clone(req.Context())
+		var timings *requestTimings
+		if s.TraceTimings {
+			attemptReq, timings = traceRequest(attemptReq)
+		}
+		resp, err := s.Client.Do(attemptReq)
+		if timings != nil {
+			log.Printf("Timings for %s (attempt %d): %s", req.URL, attempt+1, timings)
+			s.Metrics.observeTimings(timings)
+		}
+		if err == nil {
+			s.Metrics.observeResponse(resp.StatusCode)
+			s.Concurrency.observe(resp.StatusCode)
+		}
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt >= s.MaxRetries || req.Context().Err() != nil {
+			return resp, proxyError(err)
+		}
+
+		var backoff time.Duration
+		if s.Backoff != nil {
+			backoff = s.Backoff.NextDelay(attempt)
+		}
+		if err != nil {
+			log.Printf("Request to %s failed (%v), retrying in %s", req.URL, err, backoff)
+		} else {
+			log.Printf("Request to %s returned %s, retrying in %s", req.URL, resp.Status, backoff)
+			resp.Body.Close()
+		}
+		if !sleepContext(req.Context(), backoff) {
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+var (
+	// ErrNoResults is returned when eBay reports that a search has no matches
+	ErrNoResults = errors.New("no matching listings on eBay")
+	// ErrNoListings is returned when a results page has no readable listings although eBay
+	// doesn't report an empty search, which usually means the page layout changed or the
+	// request was blocked
+	ErrNoListings = errors.New("no listings could be parsed, the selectors may be outdated")
+)
+
+// Scrape performs the actual web scraping of eBay search results.
+// An empty result is returned with ErrNoResults or ErrNoListings, telling a search
+// without matches apart from a page that couldn't be read.
+func (s *Scraper) Scrape(url string) ([]Item, error) {
+	return s.ScrapeContext(context.Background(), url)
+}
+
+// ScrapeContext is like Scrape, aborting the request when ctx is cancelled
+func (s *Scraper) ScrapeContext(ctx context.Context, url string) ([]Item, error) {
+	items, listings, err := s.scrapePage(ctx, url)
+	if err == nil && listings == 0 {
+		return nil, ErrNoListings
+	}
+	return items, err
+}
+
+// isNoResultsPage checks for eBay's notice that a search has no matches, or a result count of 0
+func isNoResultsPage(doc *goquery.Document, sel Selectors) bool {
+	if doc.Find(sel.NoResults).Length() > 0 {
+		return true
+	}
+	count, ok := parseResultCount(doc.Find(sel.ResultCount).First().Text())
+	return ok && count == 0
+}
+
+// scrapePage scrapes a single results page, returning the matching items and the
+// number of listings found on the page before filtering
+func (s *Scraper) scrapePage(ctx context.Context, url string) ([]Item, int, error) {
+	marketplace, err := s.marketplace()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := s.newRequest(ctx, url, marketplace)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cached := false
+	if s.Cache != nil {
+		cached = s.Cache.applyValidators(req)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer resp.Body.Close()
 
+	// Page unchanged since the last fetch, reuse the parsed results
+	if resp.StatusCode == http.StatusNotModified && cached {
+		items, listings := s.Cache.cachedItems(url)
+		return items, listings, nil
+	}
+
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
+		return nil, 0, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
 	}
 
 	doc, err := goquery.NewDocumentFromReader(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
+	sel := s.Selectors.withDefaults()
 	var items []Item
-	doc.Find(".s-item").Each(func(i int, selection *goquery.Selection) {
-		title := selection.Find(".s-item__title").Text()
-		price := selection.Find(".s-item__price").Text()
-		url, _ := selection.Find("a.s-item__link").Attr("href")
-		watchersText := selection.Find(".s-item__watchcount").Text()
-		timeLeft := selection.Find(".s-item__time-left").Text()
+	listings := 0
+	cards := doc.Find(sel.Item)
+	cards.Each(func(i int, selection *goquery.Selection) {
+		title := selection.Find(sel.Title).Text()
+		subtitle := strings.TrimSpace(selection.Find(sel.Subtitle).Text())
+		condition := strings.TrimSpace(selection.Find(sel.Condition).First().Text())
+		price := selection.Find(sel.Price).Text()
+		url, _ := selection.Find(sel.Link).Attr("href")
+		watchersText := selection.Find(sel.Watchers).Text()
+		bidsText := selection.Find(sel.Bids).Text()
+		shippingText := strings.TrimSpace(selection.Find(sel.Shipping).First().Text())
+		timeLeft := selection.Find(sel.TimeLeft).Text()
 
 		title = cleanTitle(title)
-		priceValue := parsePrice(price)
-		isAuction := isAuction(selection)
+		rate, currency := s.exchangeRate(parseCurrency(price, marketplace.Currency))
+		priceValue := convertPrice(parsePrice(price, marketplace.DecimalComma), rate)
+		isAuction := isAuction(selection, sel)
 		watchers := parseWatchers(watchersText)
+		isBundle, bundleQuantity := parseBundle(title, subtitle)
+		packSize, packUnit := parsePackSize(title + " " + subtitle)
+		quantity := parseQuantity(title + " " + subtitle)
+		details := cardDetailsText(selection, sel)
+		returnsAccepted, freeReturns, returnsKnown := parseReturns(details)
+		sellerName, sellerFeedback, sellerRating := parseSellerInfo(selection.Find(sel.SellerInfo).Text(), marketplace.DecimalComma)
 
 		item := Item{
-			Title:      title,
-			Price:      price,
-			PriceValue: priceValue,
-			URL:        url,
-			IsAuction:  isAuction,
-			Watchers:   watchers,
-			TimeLeft:   timeLeft,
-		}
-
-		timeRange := parseTimeLeft(timeLeft)
-		if isValidItem(title, price, url) &&
-			s.isInPriceRange(priceValue) &&
-			s.shouldIncludeItem(item) &&
-			s.isInTimeRange(timeRange) {
+			ID:             parseItemID(url),
+			Title:          title,
+			Subtitle:       subtitle,
+			Condition:      condition,
+			Price:          price,
+			PriceValue:     priceValue,
+			PriceHigh:      convertPrice(parsePriceHigh(price, marketplace.DecimalComma), rate),
+			PriceCents:     toCents(priceValue),
+			Currency:       currency,
+			ShippingCost:   convertPrice(parseShipping(shippingText, marketplace.DecimalComma), rate),
+			ShippingMax:    convertPrice(parseShippingMax(shippingText, marketplace.DecimalComma), rate),
+			ShippingText:   shippingText,
+			URL:            url,
+			IsAuction:      isAuction,
+			Watchers:       watchers,
+			Bids:           parseBids(bidsText),
+			TimeLeft:       timeLeft,
+			HasVideo:       hasVideo(selection, sel),
+			TopRatedSeller: isTopRatedSeller(selection, sel),
+			EbayPlus:       isEbayPlus(selection, sel),
+			AcceptsOffers:  !isAuction && acceptsOffers(selection, sel),
+			Sponsored:      isSponsored(selection, sel),
+			IsBundle:       isBundle,
+			BundleQuantity: bundleQuantity,
+
+			QuantityAvailable: parseQuantityAvailable(details),
+
+			ReturnsAccepted: returnsAccepted,
+			FreeReturns:     freeReturns,
+			ReturnsKnown:    returnsKnown,
+
+			SellerName:     sellerName,
+			SellerFeedback: sellerFeedback,
+			SellerRating:   sellerRating,
+			ItemLocation:   strings.TrimSpace(selection.Find(sel.Location).First().Text()),
+
+			PackSize:        packSize,
+			PackUnit:        packUnit,
+			NormalizedPrice: normalizedPrice(priceValue, packSize, packUnit),
+
+			Quantity:     quantity,
+			PricePerUnit: priceValue / float64(quantity),
+		}
+
+		if !isValidItem(title, price, url) {
+			return
+		}
+		listings++
+
+		timeRange := parseTimeLeft(timeLeft, marketplace.German)
+		if item.PriceCents >= 0 && s.matchesKeywords(item.Title) && s.matchesFilters(item, timeRange) {
 			items = append(items, item)
 		}
 	})
 
-	return items, nil
+	if listings == 0 && isNoResultsPage(doc, sel) {
+		return nil, 0, ErrNoResults
+	}
+	if listings == 0 && cards.Length() > 0 {
+		log.Printf("WARNING: %d listing cards on %s but none could be parsed, the selectors are probably outdated", cards.Length(), url)
+	}
+
+	if s.Cache != nil {
+		s.Cache.store(url, resp, items, listings)
+	}
+
+	return items, listings, nil
 }
 
-// ScrapeQuery constructs the eBay search URL and initiates scraping
+// ScrapeQuery constructs the eBay search URL and scrapes up to MaxPages result pages.
+// Pagination stops early at the first page without listings, or with MinPageHitRatio
+// after the first page where too few listings pass the filters. If a page fails, the
+// items collected from the previous pages are returned together with the error.
+// With SortByUnitPrice the items are ordered by price per unit. A search without
+// matches returns ErrNoResults, one where no listing could be read ErrNoListings.
 func (s *Scraper) ScrapeQuery(query string) ([]Item, error) {
-	url := fmt.Sprintf("https://www.ebay.de/sch/i.html?_nkw=%s", strings.ReplaceAll(query, " ", "+"))
-	return s.Scrape(url)
+	return s.ScrapeQueryContext(context.Background(), query)
+}
+
+// ScrapeQueryContext is like ScrapeQuery, aborting the remaining requests when ctx
+// is cancelled. The items of the pages fetched so far are returned with ctx's error.
+func (s *Scraper) ScrapeQueryContext(ctx context.Context, query string) ([]Item, error) {
+	items, err := s.scrapePages(ctx, query)
+	if errors.Is(err, ErrNoResults) {
+		s.Metrics.observeScrape(query, nil)
+	} else {
+		s.Metrics.observeScrape(query, err)
+	}
+	if s.SortByUnitPrice {
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].PricePerUnit < items[j].PricePerUnit
+		})
+	}
+	return items, err
+}
+
+// scrapePages fetches and deduplicates the result pages of a query for ScrapeQuery
+func (s *Scraper) scrapePages(ctx context.Context, query string) ([]Item, error) {
+	baseURL, err := s.searchURL(query)
+	if err != nil {
+		return nil, err
+	}
+
+	maxPages := s.MaxPages
+	if maxPages < 1 {
+		maxPages = 1
+	}
+
+	var items []Item
+	seen := make(map[string]bool)
+	totalListings := 0
+	for page := 1; page <= maxPages; page++ {
+		url := baseURL
+		if page > 1 {
+			url = fmt.Sprintf("%s&_pgn=%d", baseURL, page)
+		}
+
+		pageItems, listings, err := s.scrapePage(ctx, url)
+		if errors.Is(err, ErrNoResults) && page == 1 {
+			return nil, err
+		}
+		if errors.Is(err, ErrNoResults) {
+			break
+		}
+		if err != nil {
+			return items, fmt.Errorf("page %d: %w", page, err)
+		}
+		if listings == 0 {
+			break
+		}
+		totalListings += listings
+
+		// Listings can move between pages while paginating
+		for _, item := range pageItems {
+			if !seen[item.ID] {
+				seen[item.ID] = true
+				items = append(items, item)
+			}
+		}
+		if s.MinPageHitRatio > 0 && float64(len(pageItems)) < s.MinPageHitRatio*float64(listings) {
+			break
+		}
+	}
+	if totalListings == 0 {
+		return nil, ErrNoListings
+	}
+	return items, nil
+}
+
+// marketplace resolves the configured marketplace
+func (s *Scraper) marketplace() (Marketplace, error) {
+	return lookupMarketplace(s.Marketplace)
+}
+
+// searchURL builds the eBay search URL for a query on the configured marketplace
+func (s *Scraper) searchURL(query string) (string, error) {
+	marketplace, err := s.marketplace()
+	if err != nil {
+		return "", err
+	}
+	sop, err := sortParam(s.SortOrder)
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("https://%s/sch/i.html?_nkw=%s", marketplace.Host, strings.ReplaceAll(query, " ", "+"))
+	if sop != 0 {
+		url = fmt.Sprintf("%s&_sop=%d", url, sop)
+	}
+	if s.CategoryID > 0 {
+		url = fmt.Sprintf("%s&_sacat=%d", url, s.CategoryID)
+	}
+	return url, nil
+}
+
+// Matches eBay's result count heading, e.g. "1.234 Ergebnisse" or "1,234 results"
+var resultCountRe = regexp.MustCompile(`(?i)(\d{1,3}(?:[.,]\d{3})+|\d+)\+?\s+(?:ergebnisse|ergebnis|results|result)`)
+
+// parseResultCount extracts the total number of results from the count heading text
+func parseResultCount(text string) (int, bool) {
+	matches := resultCountRe.FindStringSubmatch(text)
+	if len(matches) < 2 {
+		return 0, false
+	}
+	digits := strings.NewReplacer(".", "", ",", "").Replace(matches[1])
+	count, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0, false
+	}
+	return count, true
+}
+
+// ScrapeResultCount fetches the search page for a query and returns only eBay's
+// reported total number of results, without parsing the individual listings
+func (s *Scraper) ScrapeResultCount(query string) (int, error) {
+	return s.ScrapeResultCountContext(context.Background(), query)
+}
+
+// ScrapeResultCountContext is like ScrapeResultCount, aborting the request when ctx is cancelled
+func (s *Scraper) ScrapeResultCountContext(ctx context.Context, query string) (int, error) {
+	marketplace, err := s.marketplace()
+	if err != nil {
+		return 0, err
+	}
+	url, err := s.searchURL(query)
+	if err != nil {
+		return 0, err
+	}
+	req, err := s.newRequest(ctx, url, marketplace)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return 0, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	if count, ok := parseResultCount(doc.Find(s.Selectors.withDefaults().ResultCount).First().Text()); ok {
+		return count, nil
+	}
+	if count, ok := parseResultCount(doc.Find("h1").First().Text()); ok {
+		return count, nil
+	}
+	return 0, fmt.Errorf("result count not found on page")
 }
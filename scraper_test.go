@@ -0,0 +1,611 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParseTimeLeft(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		german bool
+		want   *TimeRange
+	}{
+		{"days and hours", "5T 12Std", true, &TimeRange{Days: 5, Hours: 12}},
+		{"single day", "Noch 1 Tag", true, &TimeRange{Days: 1}},
+		{"day and hours", "Noch 1 Tag 3 Std", true, &TimeRange{Days: 1, Hours: 3}},
+		{"minutes", "30 Min", true, &TimeRange{Minutes: 30}},
+		{"all units", "1T 0Std 5 Min", true, &TimeRange{Days: 1, Minutes: 5}},
+		{"seconds only", "30 Sek", true, &TimeRange{}},
+		{"english days and hours", "2d 3h", false, &TimeRange{Days: 2, Hours: 3}},
+		{"english minutes", "45m", false, &TimeRange{Minutes: 45}},
+		{"english words", "2 days 3 hours left", false, &TimeRange{Days: 2, Hours: 3}},
+		{"empty", "", true, nil},
+		{"no unit", "Sofort-Kaufen", true, nil},
+		{"day unit inside a word", "2 Teile", true, nil},
+		{"minute unit inside a word", "5 Minis", true, nil},
+		{"day unit after a model number", "Modell A5T", true, nil},
+		{"english no unit", "Buy It Now", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTimeLeft(tt.input, tt.german)
+			if got == nil || tt.want == nil {
+				if got != tt.want {
+					t.Errorf("parseTimeLeft(%q) = %v, want %v", tt.input, got, tt.want)
+				}
+				return
+			}
+			if *got != *tt.want {
+				t.Errorf("parseTimeLeft(%q) = %v, want %v", tt.input, *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestToCents(t *testing.T) {
+	tests := []struct {
+		price float64
+		want  int64
+	}{
+		{19.99, 1999},
+		{0.1 + 0.2, 30},
+		{1.005 * 1000, 100500},
+		{0, 0},
+		{-1, -1},
+	}
+	for _, tt := range tests {
+		if got := toCents(tt.price); got != tt.want {
+			t.Errorf("toCents(%v) = %d, want %d", tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestIsInPriceRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max float64
+		price    float64
+		want     bool
+	}{
+		{"price equal to max", -1, 19.99, 19.99, true},
+		{"price one cent above max", -1, 19.99, 20.00, false},
+		{"price equal to min", 19.99, -1, 19.99, true},
+		{"price one cent below min", 19.99, -1, 19.98, false},
+		{"float artifact at max", -1, 0.3, 0.1 + 0.2, true},
+		{"float artifact at min", 0.3, -1, 0.1 + 0.2, true},
+		{"min equal to max", 10, 10, 10, true},
+		{"min equal to max, price above", 10, 10, 10.01, false},
+		{"no limits", -1, -1, 5, true},
+		{"no price", -1, -1, -1, false},
+		{"no price within limits", 0, 100, -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraper()
+			s.MinPrice, s.MaxPrice = tt.min, tt.max
+			if got := s.isInPriceRange(toCents(tt.price)); got != tt.want {
+				t.Errorf("isInPriceRange(%v) with range %v..%v = %v, want %v", tt.price, tt.min, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesPriceRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max float64
+		overlap  bool
+		item     Item
+		want     bool
+	}{
+		{"single price at max", -1, 19.99, false, Item{PriceValue: 19.99, PriceHigh: 19.99}, true},
+		{"range compared by low price", -1, 15, false, Item{PriceValue: 10, PriceHigh: 20}, true},
+		{"range below min without overlap", 15, -1, false, Item{PriceValue: 10, PriceHigh: 20}, false},
+		{"range overlapping min", 15, -1, true, Item{PriceValue: 10, PriceHigh: 20}, true},
+		{"range high equal to min", 20, -1, true, Item{PriceValue: 10, PriceHigh: 20}, true},
+		{"range above max", -1, 9.99, true, Item{PriceValue: 10, PriceHigh: 20}, false},
+		{"no price", -1, -1, true, Item{PriceValue: -1, PriceHigh: -1}, false},
+		{"high within the same cent as low", 10, -1, true, Item{PriceValue: 9.99, PriceHigh: 9.990001}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraper()
+			s.MinPrice, s.MaxPrice, s.PriceRangeOverlap = tt.min, tt.max, tt.overlap
+			tt.item.PriceCents = toCents(tt.item.PriceValue)
+			if got := s.matchesPriceRange(tt.item); got != tt.want {
+				t.Errorf("matchesPriceRange(%+v) = %v, want %v", tt.item, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesExactTokens(t *testing.T) {
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{"MacBook Pro A1706 13 Zoll", true},
+		{"MacBook Pro A1707 15 Zoll", false},
+		{"MacBook Pro A17060", false},
+		{"macbook pro a1706", true},
+		{"MacBook Pro (A1706)", true},
+		{"MacBook Pro A1706, 2017", true},
+		{"A1706", true},
+		{"XA1706 Netzteil", false},
+	}
+	s := NewScraper()
+	s.ExactTokens = []string{"A1706"}
+	for _, tt := range tests {
+		if got := s.matchesExactTokens(tt.title); got != tt.want {
+			t.Errorf("matchesExactTokens(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
+
+// cardSelection parses a listing card for the tests of the card helpers
+func cardSelection(t *testing.T, html string) *goquery.Selection {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc.Find(".s-item")
+}
+
+func TestIsTopRatedSeller(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{"badge", `<li class="s-item"><span class="s-item__etrs-badge"></span></li>`, true},
+		{"seller info", `<li class="s-item"><span class="s-item__seller-info-text">Top-bewerteter Verkäufer</span></li>`, true},
+		{"title only", `<li class="s-item"><div class="s-item__title">TOPSELLER Nintendo Switch</div></li>`, false},
+		{"none", `<li class="s-item"><div class="s-item__title">Nintendo Switch</div></li>`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTopRatedSeller(cardSelection(t, tt.html), DefaultSelectors()); got != tt.want {
+				t.Errorf("isTopRatedSeller = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsEbayPlus(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{"badge", `<li class="s-item"><img alt="eBay Plus"></li>`, true},
+		{"details", `<li class="s-item"><span class="s-item__detail">eBay Plus</span></li>`, true},
+		{"title only", `<li class="s-item"><div class="s-item__title">Versand wie eBay Plus</div></li>`, false},
+		{"subtitle only", `<li class="s-item"><div class="s-item__subtitle">schneller als eBay Plus</div></li>`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isEbayPlus(cardSelection(t, tt.html), DefaultSelectors()); got != tt.want {
+				t.Errorf("isEbayPlus = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReturns(t *testing.T) {
+	tests := []struct {
+		text                  string
+		accepted, free, known bool
+	}{
+		{"Kostenlose Rücknahme", true, true, true},
+		{"30 Tage Rücknahme", true, false, true},
+		{"Rücknahme möglich", true, false, true},
+		{"Keine Rücknahme", false, false, true},
+		{"Rücknahme nicht möglich", false, false, true},
+		{"Free returns", true, true, true},
+		{"30 days returns", true, false, true},
+		{"Rücknahmegarantie vom Händler", false, false, false},
+		{"Sofort-Kaufen", false, false, false},
+	}
+	for _, tt := range tests {
+		accepted, free, known := parseReturns(tt.text)
+		if accepted != tt.accepted || free != tt.free || known != tt.known {
+			t.Errorf("parseReturns(%q) = %v, %v, %v, want %v, %v, %v", tt.text, accepted, free, known, tt.accepted, tt.free, tt.known)
+		}
+	}
+}
+
+func TestParseQuantityAvailableIgnoresTitle(t *testing.T) {
+	card := cardSelection(t, `<li class="s-item"><div class="s-item__title">Set 4 verfügbar in Blau</div><span class="s-item__detail">Nur noch 2 verfügbar</span></li>`)
+	if got := parseQuantityAvailable(cardDetailsText(card, DefaultSelectors())); got != 2 {
+		t.Errorf("parseQuantityAvailable = %d, want 2", got)
+	}
+	card = cardSelection(t, `<li class="s-item"><div class="s-item__subtitle">10 verfügbar im Shop</div></li>`)
+	if got := parseQuantityAvailable(cardDetailsText(card, DefaultSelectors())); got != 0 {
+		t.Errorf("parseQuantityAvailable = %d, want 0", got)
+	}
+}
+
+func TestParseBundle(t *testing.T) {
+	tests := []struct {
+		title    string
+		bundle   bool
+		quantity int
+	}{
+		{"Pokemon Karten Lot of 50", true, 50},
+		{"Pokemon Karten x10", true, 10},
+		{"10x Nintendo DS Spiele", true, 10},
+		{"Konvolut 25 Schallplatten", true, 25},
+		{"LEGO 100 Stück Steine", true, 100},
+		{"Nintendo Spiele Paket", true, 0},
+		{"Nintendo 3DS XL 2 x", false, 0},
+		{"Monitor 27 x 15 cm", false, 0},
+		{"Poster 30x40cm", false, 0},
+		{"Switch Spiel, Paketversand", false, 0},
+		{"Switch Spiel inkl. Paketbeilage", false, 0},
+		{"Kaffee 3 x 500g", true, 3},
+		{"Kaffee 4 x 250 Gramm", true, 4},
+		{"Bilderrahmen 2 x 30x40cm", false, 0},
+		{"Batterien 3er Pack", true, 3},
+		{"Socken pack of 6", true, 6},
+	}
+	for _, tt := range tests {
+		bundle, quantity := parseBundle(tt.title, "")
+		if bundle != tt.bundle || quantity != tt.quantity {
+			t.Errorf("parseBundle(%q) = %v, %d, want %v, %d", tt.title, bundle, quantity, tt.bundle, tt.quantity)
+		}
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		title string
+		want  int
+	}{
+		{"Druckerpatronen 100 Stück", 100},
+		{"Batterien AA 3er Pack", 3},
+		{"Socken pack of 6", 6},
+		{"Kaffee 3 x 500g", 3},
+		{"Pokemon Karten x10", 10},
+		{"Poster 30x40cm", 1},
+		{"Nintendo 3DS XL 2 x", 1},
+		{"Kaffeebohnen 1kg", 1},
+		{"MacBook Pro 13 Zoll", 1},
+	}
+	for _, tt := range tests {
+		if got := parseQuantity(tt.title); got != tt.want {
+			t.Errorf("parseQuantity(%q) = %d, want %d", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestScrapePageQuantity(t *testing.T) {
+	tests := []struct {
+		title    string
+		quantity int
+	}{
+		{"Pokemon Karten x10", 10},
+		{"Batterien 3er Pack", 3},
+		{"Schrauben 100 Stück", 100},
+		{"Nintendo Switch", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `<ul><li class="s-item"><div class="s-item__title">%s</div><span class="s-item__price">EUR 10,00</span><a class="s-item__link" href="https://www.ebay.de/itm/1">x</a></li></ul>`, tt.title)
+			}))
+			defer server.Close()
+
+			items, _, err := NewScraper().scrapePage(context.Background(), server.URL)
+			if err != nil || len(items) != 1 {
+				t.Fatalf("scrapePage = %v, %v", items, err)
+			}
+			item := items[0]
+			if item.Quantity != tt.quantity {
+				t.Errorf("Quantity = %d, want %d", item.Quantity, tt.quantity)
+			}
+			if item.BundleQuantity > 1 && item.BundleQuantity != item.Quantity {
+				t.Errorf("BundleQuantity = %d differs from Quantity = %d", item.BundleQuantity, item.Quantity)
+			}
+			if want := 10 / float64(tt.quantity); item.PricePerUnit != want {
+				t.Errorf("PricePerUnit = %v, want %v", item.PricePerUnit, want)
+			}
+		})
+	}
+}
+
+// redirectTransport sends every request to a test server, keeping its path and query
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// listingCard renders a result card with the given item id and title
+func listingCard(id, title string) string {
+	return fmt.Sprintf(`<li class="s-item"><div class="s-item__title">%s</div><span class="s-item__price">EUR 10,00</span><a class="s-item__link" href="https://www.ebay.de/itm/%s">x</a></li>`, title, id)
+}
+
+// pagedScraper returns a scraper whose requests go to a server answering with pages[n-1]
+// for result page n, a 404 for pages past the end, and the list of requested page numbers
+func pagedScraper(t *testing.T, pages []string) (*Scraper, *[]int) {
+	t.Helper()
+	var requested []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := 1
+		if pgn := r.URL.Query().Get("_pgn"); pgn != "" {
+			page, _ = strconv.Atoi(pgn)
+		}
+		requested = append(requested, page)
+		if page > len(pages) || pages[page-1] == "" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "<ul>%s</ul>", pages[page-1])
+	}))
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	scraper := NewScraper()
+	scraper.Client = &http.Client{Transport: redirectTransport{target}}
+	scraper.MaxRetries = 0
+	return scraper, &requested
+}
+
+// itemIDs returns the ids of the items in order
+func itemIDs(items []Item) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+func TestScrapePages(t *testing.T) {
+	tests := []struct {
+		name      string
+		pages     []string
+		maxPages  int
+		wantIDs   []string
+		wantPages []int
+		wantErr   bool
+	}{
+		{
+			name:      "stops at an empty page",
+			pages:     []string{listingCard("1", "Switch"), " ", listingCard("3", "Switch")},
+			maxPages:  5,
+			wantIDs:   []string{"1"},
+			wantPages: []int{1, 2},
+		},
+		{
+			name:      "capped by MaxPages",
+			pages:     []string{listingCard("1", "Switch"), listingCard("2", "Switch"), listingCard("3", "Switch")},
+			maxPages:  2,
+			wantIDs:   []string{"1", "2"},
+			wantPages: []int{1, 2},
+		},
+		{
+			name:      "keeps earlier pages when a page fails",
+			pages:     []string{listingCard("1", "Switch"), listingCard("2", "Switch")},
+			maxPages:  4,
+			wantIDs:   []string{"1", "2"},
+			wantPages: []int{1, 2, 3},
+			wantErr:   true,
+		},
+		{
+			name:      "deduplicates items moving between pages",
+			pages:     []string{listingCard("1", "Switch") + listingCard("2", "Switch"), listingCard("2", "Switch") + listingCard("3", "Switch")},
+			maxPages:  2,
+			wantIDs:   []string{"1", "2", "3"},
+			wantPages: []int{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scraper, requested := pagedScraper(t, tt.pages)
+			scraper.MaxPages = tt.maxPages
+
+			items, err := scraper.ScrapeQuery("switch")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ScrapeQuery error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := itemIDs(items); !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("item ids = %v, want %v", got, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(*requested, tt.wantPages) {
+				t.Errorf("requested pages = %v, want %v", *requested, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestScrapePagesMinPageHitRatio(t *testing.T) {
+	// Page 1 has 2 of 2 matches, page 2 only 1 of 4, so page 3 is never fetched
+	pages := []string{
+		listingCard("1", "Switch OLED") + listingCard("2", "Switch OLED"),
+		listingCard("3", "Switch OLED") + listingCard("4", "Switch Hülle") + listingCard("5", "Switch Tasche") + listingCard("6", "Switch Folie"),
+		listingCard("7", "Switch OLED"),
+	}
+	tests := []struct {
+		name      string
+		ratio     float64
+		wantIDs   []string
+		wantPages []int
+	}{
+		{"disabled", 0, []string{"1", "2", "3", "7"}, []int{1, 2, 3}},
+		{"stops below the ratio", 0.5, []string{"1", "2", "3"}, []int{1, 2}},
+		{"continues at the ratio", 0.25, []string{"1", "2", "3", "7"}, []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scraper, requested := pagedScraper(t, pages)
+			scraper.MaxPages = 3
+			scraper.ExactTokens = []string{"OLED"}
+			scraper.MinPageHitRatio = tt.ratio
+
+			items, err := scraper.ScrapeQuery("switch")
+			if err != nil {
+				t.Fatalf("ScrapeQuery: %v", err)
+			}
+			if got := itemIDs(items); !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("item ids = %v, want %v", got, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(*requested, tt.wantPages) {
+				t.Errorf("requested pages = %v, want %v", *requested, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		decimalComma bool
+		want         float64
+	}{
+		{"usd thousands", "$1,234.56", false, 1234.56},
+		{"usd", "$12.50", false, 12.5},
+		{"usd with code", "US $1,234,567.89", false, 1234567.89},
+		{"usd thousands without decimals", "$1,234", false, 1234},
+		{"usd range", "$10.00 to $20.00", false, 10},
+		{"eur thousands", "EUR 1.234,56", true, 1234.56},
+		{"eur", "EUR 12,50", true, 12.5},
+		{"eur thousands without decimals", "EUR 1.234", true, 1234},
+		{"eur decimal point on a comma site", "EUR 12.50", true, 12.5},
+		{"usd on a comma site", "$1,234.56", true, 1234.56},
+		{"eur on a point site", "EUR 1.234,56", false, 1234.56},
+		{"eur range", "EUR 10,00 bis EUR 20,00", true, 10},
+		{"no amount", "Preis auf Anfrage", true, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePrice(tt.text, tt.decimalComma); got != tt.want {
+				t.Errorf("parsePrice(%q, %v) = %v, want %v", tt.text, tt.decimalComma, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseShippingRange(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		decimalComma bool
+		low, high    float64
+	}{
+		{"german range", "+EUR 4,99 bis EUR 6,99 Versand", true, 4.99, 6.99},
+		{"german single", "+EUR 4,99 Versand", true, 4.99, 4.99},
+		{"german free", "Kostenloser Versand", true, 0, 0},
+		{"us range", "+$4.99 to $12.50 shipping", false, 4.99, 12.5},
+		{"us shipping to a country", "+$20.00 shipping to Germany", false, 20, 20},
+		{"uk range", "+£2.50 to £4.00 postage", false, 2.5, 4},
+		{"unknown", "Versand nicht angegeben", true, -1, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseShipping(tt.text, tt.decimalComma); got != tt.low {
+				t.Errorf("parseShipping(%q) = %v, want %v", tt.text, got, tt.low)
+			}
+			if got := parseShippingMax(tt.text, tt.decimalComma); got != tt.high {
+				t.Errorf("parseShippingMax(%q) = %v, want %v", tt.text, got, tt.high)
+			}
+		})
+	}
+}
+
+func TestScrapePageShippingRange(t *testing.T) {
+	tests := []struct {
+		marketplace string
+		price       string
+		shipping    string
+		low, high   float64
+	}{
+		{"ebay.de", "EUR 20,00", "+EUR 4,99 bis EUR 9,99 Versand", 4.99, 9.99},
+		{"ebay.com", "$20.00", "+$4.99 to $9.99 shipping", 4.99, 9.99},
+		{"ebay.co.uk", "£20.00", "+£4.99 to £9.99 postage", 4.99, 9.99},
+	}
+	for _, tt := range tests {
+		t.Run(tt.marketplace, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `<ul><li class="s-item"><div class="s-item__title">Switch</div><span class="s-item__price">%s</span><span class="s-item__shipping">%s</span><a class="s-item__link" href="https://www.%s/itm/1">x</a></li></ul>`,
+					tt.price, tt.shipping, tt.marketplace)
+			}))
+			defer server.Close()
+
+			// A total of 20 plus the lowest shipping cost fits below 25, plus the highest doesn't
+			for _, useMax := range []bool{false, true} {
+				scraper := NewScraper()
+				scraper.Marketplace = tt.marketplace
+				scraper.UseTotalPrice = true
+				scraper.UseMaxShipping = useMax
+				scraper.MaxPrice = 25
+
+				items, _, err := scraper.scrapePage(context.Background(), server.URL)
+				if err != nil {
+					t.Fatalf("scrapePage: %v", err)
+				}
+				if want := !useMax; (len(items) == 1) != want {
+					t.Fatalf("UseMaxShipping=%v: got %d items, want match %v", useMax, len(items), want)
+				}
+				if len(items) == 1 && (items[0].ShippingCost != tt.low || items[0].ShippingMax != tt.high) {
+					t.Errorf("shipping = %v to %v, want %v to %v", items[0].ShippingCost, items[0].ShippingMax, tt.low, tt.high)
+				}
+			}
+		})
+	}
+}
+
+// recordingBackoff records the attempts it is asked about and never waits
+type recordingBackoff struct {
+	attempts []int
+}
+
+func (b *recordingBackoff) NextDelay(attempt int) time.Duration {
+	b.attempts = append(b.attempts, attempt)
+	return 0
+}
+
+func TestDoUsesBackoffStrategy(t *testing.T) {
+	// Two rate limit responses followed by success
+	responses := []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusOK}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(responses[requests])
+		requests++
+	}))
+	defer server.Close()
+
+	backoff := &recordingBackoff{}
+	scraper := NewScraper()
+	scraper.Backoff = backoff
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := scraper.do(req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if want := []int{0, 1}; !reflect.DeepEqual(backoff.attempts, want) {
+		t.Errorf("backoff asked for attempts %v, want %v", backoff.attempts, want)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Package main provides the CSS selectors used to read eBay's result pages.
+They are kept in one place so a selector broken by an eBay HTML change can be
+patched in the configuration without recompiling.
+*/
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andybalholm/cascadia"
+)
+
+/*
+Selectors holds the CSS selectors for the parts of a result page.
+Item selects the listing cards, the other item selectors are applied within a card.
+Empty fields use the defaults of DefaultSelectors.
+*/
+type Selectors struct {
+	Item            string `json:"item,omitempty"`
+	Title           string `json:"title,omitempty"`
+	Subtitle        string `json:"subtitle,omitempty"`
+	Condition       string `json:"condition,omitempty"`
+	Price           string `json:"price,omitempty"`
+	Link            string `json:"link,omitempty"`
+	Watchers        string `json:"watchers,omitempty"`
+	Bids            string `json:"bids,omitempty"`
+	Shipping        string `json:"shipping,omitempty"`
+	TimeLeft        string `json:"time_left,omitempty"`
+	SellerInfo      string `json:"seller_info,omitempty"`
+	Location        string `json:"location,omitempty"`
+	TopRatedBadge   string `json:"top_rated_badge,omitempty"`
+	BestOffer       string `json:"best_offer,omitempty"`
+	PurchaseOptions string `json:"purchase_options,omitempty"`
+	SponsoredLabel  string `json:"sponsored_label,omitempty"`
+	Video           string `json:"video,omitempty"`
+	EbayPlusBadge   string `json:"ebay_plus_badge,omitempty"`
+	ResultCount     string `json:"result_count,omitempty"`
+	NoResults       string `json:"no_results,omitempty"`
+}
+
+// DefaultSelectors returns the selectors matching eBay's current result pages
+func DefaultSelectors() Selectors {
+	return Selectors{
+		Item:            ".s-item",
+		Title:           ".s-item__title",
+		Subtitle:        ".s-item__subtitle",
+		Condition:       ".SECONDARY_INFO",
+		Price:           ".s-item__price",
+		Link:            "a.s-item__link",
+		Watchers:        ".s-item__watchcount",
+		Bids:            ".s-item__bids",
+		Shipping:        ".s-item__shipping, .s-item__logisticsCost",
+		TimeLeft:        ".s-item__time-left",
+		SellerInfo:      ".s-item__seller-info-text",
+		Location:        ".s-item__location, .s-item__itemLocation",
+		TopRatedBadge:   ".s-item__etrs-badge, .s-item__etrs-text",
+		BestOffer:       ".s-item__formatBestOfferEnabled",
+		PurchaseOptions: ".s-item__purchase-options, .s-item__purchaseOptions, .s-item__details",
+		SponsoredLabel:  ".s-item__sep, .s-item__title--tagblock",
+		Video:           "[class*='video'], [aria-label*='Video'], [aria-label*='video']",
+		EbayPlusBadge:   "[class*='ebayplus'], [class*='ebay-plus'], img[alt*='eBay Plus']",
+		ResultCount:     ".srp-controls__count-heading",
+		NoResults:       ".srp-save-null-search",
+	}
+}
+
+// fields returns the configuration name and a pointer to every selector
+func (s *Selectors) fields() []struct {
+	name  string
+	value *string
+} {
+	return []struct {
+		name  string
+		value *string
+	}{
+		{"item", &s.Item},
+		{"title", &s.Title},
+		{"subtitle", &s.Subtitle},
+		{"condition", &s.Condition},
+		{"price", &s.Price},
+		{"link", &s.Link},
+		{"watchers", &s.Watchers},
+		{"bids", &s.Bids},
+		{"shipping", &s.Shipping},
+		{"time_left", &s.TimeLeft},
+		{"seller_info", &s.SellerInfo},
+		{"location", &s.Location},
+		{"top_rated_badge", &s.TopRatedBadge},
+		{"best_offer", &s.BestOffer},
+		{"purchase_options", &s.PurchaseOptions},
+		{"sponsored_label", &s.SponsoredLabel},
+		{"video", &s.Video},
+		{"ebay_plus_badge", &s.EbayPlusBadge},
+		{"result_count", &s.ResultCount},
+		{"no_results", &s.NoResults},
+	}
+}
+
+// withDefaults returns the selectors with empty fields set to their defaults
+func (s Selectors) withDefaults() Selectors {
+	defaults := DefaultSelectors()
+	defaultFields := defaults.fields()
+	for i, field := range s.fields() {
+		if *field.value == "" {
+			*field.value = *defaultFields[i].value
+		}
+	}
+	return s
+}
+
+// validate checks that every configured selector is valid CSS
+func (s *Selectors) validate() error {
+	var problems []error
+	for _, field := range s.fields() {
+		if *field.value == "" {
+			continue
+		}
+		if _, err := cascadia.ParseGroup(*field.value); err != nil {
+			problems = append(problems, fmt.Errorf("selectors: %s: %w", field.name, err))
+		}
+	}
+	return errors.Join(problems...)
+}
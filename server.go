@@ -0,0 +1,157 @@
+/*
+Package main provides an optional HTTP server exposing the monitor's state.
+It streams the findings as JSON, serves a health endpoint and optionally Prometheus metrics.
+*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+findingsServer serves the findings saved to a findings file over HTTP, reading the
+file one record at a time on every request rather than holding it in memory.
+Findings that aren't written to the file, as in a dry run, are kept in memory instead.
+All methods are safe for concurrent use and do nothing on a nil server.
+*/
+type findingsServer struct {
+	path      string
+	mu        sync.RWMutex
+	unsaved   []SavedItem
+	lastCheck time.Time
+}
+
+// newFindingsServer creates a server for the findings file at path
+func newFindingsServer(path string) *findingsServer {
+	return &findingsServer{path: path}
+}
+
+// addUnsaved records findings that aren't written to the findings file
+func (f *findingsServer) addUnsaved(saved []SavedItem) {
+	if f == nil || len(saved) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unsaved = append(f.unsaved, saved...)
+}
+
+// setLastCheck records the time of the last successful check
+func (f *findingsServer) setLastCheck(t time.Time) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastCheck = t
+}
+
+// handleFindings serves the findings as a JSON array, optionally filtered by ?query=.
+// The records are encoded as they are read from the findings file.
+func (f *findingsServer) handleFindings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query().Get("query")
+
+	w.Header().Set("Content-Type", "application/json")
+	out := bufio.NewWriter(w)
+	out.WriteString("[")
+	count := 0
+	write := func(saved SavedItem) error {
+		if query != "" && saved.QueryTerm != query {
+			return nil
+		}
+		data, err := json.Marshal(saved)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			out.WriteString(",")
+		}
+		count++
+		_, err = out.Write(data)
+		return err
+	}
+
+	if err := eachFinding(f.path, write); err != nil && !errors.Is(err, os.ErrNotExist) {
+		// The response has started, so abort it rather than end a truncated array
+		log.Printf("Error serving findings: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+	f.mu.RLock()
+	unsaved := f.unsaved
+	f.mu.RUnlock()
+	for _, saved := range unsaved {
+		if err := write(saved); err != nil {
+			log.Printf("Error serving findings: %v", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+	out.WriteString("]\n")
+	if err := out.Flush(); err != nil {
+		log.Printf("Error writing HTTP response: %v", err)
+	}
+}
+
+// handleHealth reports that the monitor is running and when it last checked successfully
+func (f *findingsServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	f.mu.RLock()
+	lastCheck := f.lastCheck
+	f.mu.RUnlock()
+
+	health := struct {
+		Status    string     `json:"status"`
+		LastCheck *time.Time `json:"last_check"`
+	}{Status: "ok"}
+	if !lastCheck.IsZero() {
+		health.LastCheck = &lastCheck
+	}
+	writeJSON(w, health)
+}
+
+// writeJSON encodes v as the JSON response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing HTTP response: %v", err)
+	}
+}
+
+// serve listens on addr in the background until ctx is cancelled, adding /metrics
+// when metrics are given
+func (f *findingsServer) serve(ctx context.Context, addr string, metrics *Metrics) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/findings", f.handleFindings)
+	mux.HandleFunc("/healthz", f.handleHealth)
+	if metrics != nil {
+		mux.Handle("/metrics", metrics.handler())
+	}
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Error running HTTP server: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHandleFindingsStreamsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findings.json")
+	found := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	saved := []SavedItem{
+		{Item: Item{Title: "Switch OLED", URL: "https://www.ebay.de/itm/1"}, Found: found, QueryTerm: "switch"},
+		{Item: Item{Title: "LEGO 42115", URL: "https://www.ebay.de/itm/2"}, Found: found, QueryTerm: "lego"},
+	}
+	if err := writeFindings(path, saved, false); err != nil {
+		t.Fatalf("writeFindings: %v", err)
+	}
+
+	server := newFindingsServer(path)
+	server.addUnsaved([]SavedItem{{Item: Item{Title: "Switch Lite", URL: "https://www.ebay.de/itm/3"}, Found: found, QueryTerm: "switch"}})
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"/findings", []string{"Switch OLED", "LEGO 42115", "Switch Lite"}},
+		{"/findings?query=switch", []string{"Switch OLED", "Switch Lite"}},
+		{"/findings?query=none", []string{}},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		server.handleFindings(recorder, httptest.NewRequest("GET", tt.target, nil))
+
+		var got []SavedItem
+		if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: invalid JSON %q: %v", tt.target, recorder.Body.String(), err)
+		}
+		titles := make([]string, len(got))
+		for i, s := range got {
+			titles[i] = s.Item.Title
+		}
+		if !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("%s: titles = %v, want %v", tt.target, titles, tt.want)
+		}
+	}
+
+	// Nothing saved yet is an empty list, not an error
+	recorder := httptest.NewRecorder()
+	newFindingsServer(filepath.Join(t.TempDir(), "missing.json")).handleFindings(recorder, httptest.NewRequest("GET", "/findings", nil))
+	if body := recorder.Body.String(); body != "[]\n" {
+		t.Errorf("missing findings file: body = %q, want []", body)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Package main provides fuzzy duplicate detection for relisted items.
+Sellers often relist an item under a new URL with a slightly reworded title,
+so titles are compared as token sets rather than exactly.
+*/
+package main
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// titleTokens splits a title into its distinct lowercase words and numbers
+func titleTokens(title string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		tokens[word] = true
+	}
+	return tokens
+}
+
+// titleSimilarity returns the share of words two titles have in common, from 0 to 1
+func titleSimilarity(a, b string) float64 {
+	tokensA, tokensB := titleTokens(a), titleTokens(b)
+	if len(tokensA) == 0 || len(tokensB) == 0 {
+		return 0
+	}
+	common := 0
+	for token := range tokensA {
+		if tokensB[token] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(tokensA)+len(tokensB)-common)
+}
+
+// similarPrice reports whether two prices differ by at most tolerance relative to the higher one.
+// Unknown prices only match each other.
+func similarPrice(a, b, tolerance float64) bool {
+	if a < 0 || b < 0 {
+		return a < 0 && b < 0
+	}
+	return math.Abs(a-b) <= tolerance*math.Max(a, b)
+}
+
+// findSimilarItem returns the id of a seen item whose title and price are within the
+// threshold of the item's, ignoring the item itself and items without a title in titles,
+// i.e. those not seen in this session. The price may differ by up to 1 - threshold of the higher price.
+func findSimilarItem(item Item, seenItems map[string]seenItem, titles map[string]string, threshold float64) (string, bool) {
+	for id, seen := range seenItems {
+		title, ok := titles[id]
+		if id == item.ID || !ok {
+			continue
+		}
+		if similarPrice(item.PriceValue, seen.Price, 1-threshold) && titleSimilarity(item.Title, title) >= threshold {
+			return id, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,167 @@
+/*
+Package main provides an optional live status display for interactive use.
+It shows each search's state in place and keeps recent output below it.
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of recent output lines kept below the status table
+const statusRecentLines = 15
+
+// searchStatus is the displayed state of a single search
+type searchStatus struct {
+	query     string
+	state     string
+	lastCount int
+	lastNew   int
+	hasRun    bool
+	nextRun   time.Time
+}
+
+/*
+statusDisplay redraws a table of search states on a terminal.
+It also acts as an io.Writer so regular output can be captured and shown
+below the table instead of scrolling it away. All methods are safe for
+concurrent use and do nothing on a nil display.
+*/
+type statusDisplay struct {
+	mu       sync.Mutex
+	out      io.Writer
+	searches []searchStatus
+	recent   []string
+	partial  string
+}
+
+// newStatusDisplay creates a display for the given searches drawing to out
+func newStatusDisplay(out io.Writer, searches []SearchConfig) *statusDisplay {
+	d := &statusDisplay{out: out}
+	for _, search := range searches {
+		d.searches = append(d.searches, searchStatus{query: search.Query, state: "waiting"})
+	}
+	return d
+}
+
+// Write captures output lines and shows them below the status table
+func (d *statusDisplay) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	lines := strings.Split(d.partial+string(p), "\n")
+	d.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		d.recent = append(d.recent, line)
+	}
+	if len(d.recent) > statusRecentLines {
+		d.recent = d.recent[len(d.recent)-statusRecentLines:]
+	}
+	d.render()
+	return len(p), nil
+}
+
+// setSearches replaces the displayed searches, keeping the state of queries shown before
+func (d *statusDisplay) setSearches(searches []SearchConfig) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	previous := make(map[string]searchStatus)
+	for _, search := range d.searches {
+		previous[search.query] = search
+	}
+	d.searches = d.searches[:0]
+	for _, search := range searches {
+		status, ok := previous[search.Query]
+		if !ok {
+			status = searchStatus{query: search.Query, state: "waiting"}
+		}
+		d.searches = append(d.searches, status)
+	}
+	d.render()
+}
+
+// setState updates the state text of a search
+func (d *statusDisplay) setState(i int, state string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.searches[i].state = state
+	d.render()
+}
+
+// setResult records the outcome of a finished scrape
+func (d *statusDisplay) setResult(i int, count int, newItems int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.searches[i].state = "done"
+	d.searches[i].lastCount = count
+	d.searches[i].lastNew = newItems
+	d.searches[i].hasRun = true
+	d.render()
+}
+
+// setNextRun sets the next run time of a search that finished its round
+func (d *statusDisplay) setNextRun(i int, next time.Time) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.searches[i].nextRun = next
+	d.searches[i].state = "waiting"
+	d.render()
+}
+
+// render redraws the whole display, the caller must hold the lock
+func (d *statusDisplay) render() {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	headerColor.Fprintf(&b, "baycheck status (updated %s)\n\n", time.Now().Format("15:04:05"))
+
+	width := len("Query")
+	for _, search := range d.searches {
+		if len(search.query) > width {
+			width = len(search.query)
+		}
+	}
+	fmt.Fprintf(&b, "%-*s  %-24s  %-22s  %s\n", width, "Query", "State", "Last result", "Next run")
+	for _, search := range d.searches {
+		result := "-"
+		if search.hasRun {
+			result = fmt.Sprintf("%d matching, %d new", search.lastCount, search.lastNew)
+		}
+		next := "-"
+		if !search.nextRun.IsZero() {
+			next = search.nextRun.Format("15:04:05")
+		}
+		state := search.state
+		if len(state) > 24 {
+			state = state[:21] + "..."
+		}
+		fmt.Fprintf(&b, "%-*s  %-24s  %-22s  %s\n", width, search.query, state, result, next)
+	}
+
+	if len(d.recent) > 0 {
+		headerColor.Fprintf(&b, "\nRecent output:\n")
+		for _, line := range d.recent {
+			b.WriteString(line + "\n")
+		}
+	}
+	io.WriteString(d.out, b.String())
+}
@@ -0,0 +1,136 @@
+/*
+Package main provides the storage backends for saved findings.
+The JSON backend keeps findings.json as a JSON array, optionally mirrored
+to one file per query.
+*/
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+/*
+Store persists the saved findings of the monitor.
+Implementations must be safe for concurrent use.
+*/
+type Store interface {
+	// Save persists a new item or price drop
+	Save(saved SavedItem) error
+	// Seen reports whether a listing was saved before, comparing item ids so
+	// changing tracking parameters in the URL don't matter
+	Seen(url string) (bool, error)
+}
+
+// batchSaver is implemented by stores that save several findings at once
+// more cheaply than one by one
+type batchSaver interface {
+	SaveAll(saved []SavedItem) error
+}
+
+// saveAll passes the findings to the store, in one batch if the store supports it
+func saveAll(store Store, saved []SavedItem) error {
+	if batch, ok := store.(batchSaver); ok {
+		return batch.SaveAll(saved)
+	}
+	for _, s := range saved {
+		if err := store.Save(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newStore creates the storage backend selected by the storage setting
+func newStore(config *Config) (Store, error) {
+	switch strings.ToLower(config.Storage) {
+	case "", "json":
+		return newJSONStore("findings.json", config.PrettyFindings, config.SeparateFiles), nil
+	}
+	return nil, fmt.Errorf("unknown storage %q, expected \"json\"", config.Storage)
+}
+
+/*
+jsonStore keeps the findings in a JSON array file, rewritten atomically on every save.
+With separateFiles each query's findings also go to findings/<query>.json.
+The item ids for Seen are read from the file on first use and kept up to date afterwards.
+*/
+type jsonStore struct {
+	path          string
+	pretty        bool
+	separateFiles bool
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// newJSONStore creates a store for the findings file at path
+func newJSONStore(path string, pretty, separateFiles bool) *jsonStore {
+	return &jsonStore{path: path, pretty: pretty, separateFiles: separateFiles}
+}
+
+// Save appends a finding to the findings file
+func (s *jsonStore) Save(saved SavedItem) error {
+	return s.SaveAll([]SavedItem{saved})
+}
+
+// SaveAll appends findings to the findings file with a single rewrite, and to the files
+// of their queries with separateFiles
+func (s *jsonStore) SaveAll(saved []SavedItem) error {
+	if len(saved) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := appendFindings(s.path, saved, s.pretty); err != nil {
+		return fmt.Errorf("saving to %s: %w", s.path, err)
+	}
+	if s.seen != nil {
+		for _, entry := range saved {
+			s.seen[parseItemID(entry.Item.URL)] = true
+		}
+	}
+	if !s.separateFiles {
+		return nil
+	}
+
+	// Keep the order of the queries, a search usually saves findings of only one
+	var queries []string
+	byQuery := make(map[string][]SavedItem)
+	for _, entry := range saved {
+		if _, ok := byQuery[entry.QueryTerm]; !ok {
+			queries = append(queries, entry.QueryTerm)
+		}
+		byQuery[entry.QueryTerm] = append(byQuery[entry.QueryTerm], entry)
+	}
+	var errs []error
+	for _, query := range queries {
+		if err := appendQueryFindings(query, byQuery[query], s.pretty); err != nil {
+			errs = append(errs, fmt.Errorf("saving to the findings of %s: %w", query, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Seen reports whether the findings file has a record of the listing
+func (s *jsonStore) Seen(url string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.seen == nil {
+		seen := make(map[string]bool)
+		err := eachFinding(s.path, func(saved SavedItem) error {
+			seen[parseItemID(saved.Item.URL)] = true
+			return nil
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+		s.seen = seen
+	}
+	return s.seen[parseItemID(url)], nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJSONStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findings.json")
+	store := newJSONStore(path, false, false)
+	findings := testFindings()
+
+	if seen, err := store.Seen(findings[0].Item.URL); err != nil || seen {
+		t.Fatalf("Seen on a missing file = %v, %v, want false, nil", seen, err)
+	}
+	if err := store.Save(findings[0]); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := saveAll(store, findings[1:]); err != nil {
+		t.Fatalf("saveAll: %v", err)
+	}
+
+	// Seen compares item ids, so tracking parameters don't make a listing new
+	for url, want := range map[string]bool{
+		"https://www.ebay.de/itm/1?hash=item1": true,
+		"https://www.ebay.de/itm/lego-42115/2": true,
+		"https://www.ebay.de/itm/3":            false,
+	} {
+		if seen, err := store.Seen(url); err != nil || seen != want {
+			t.Errorf("Seen(%s) = %v, %v, want %v, nil", url, seen, err, want)
+		}
+	}
+
+	// A new store loads the seen items from the file
+	if seen, err := newJSONStore(path, false, false).Seen(findings[1].Item.URL); err != nil || !seen {
+		t.Errorf("Seen after reopening = %v, %v, want true, nil", seen, err)
+	}
+
+	got, err := readFindings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, findings) {
+		t.Errorf("findings file = %+v, want %+v", got, findings)
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	for _, storage := range []string{"", "json", "JSON"} {
+		if _, err := newStore(&Config{Storage: storage}); err != nil {
+			t.Errorf("newStore(%q): %v", storage, err)
+		}
+	}
+	if _, err := newStore(&Config{Storage: "postgres"}); err == nil {
+		t.Error("newStore accepted an unknown storage")
+	}
+}
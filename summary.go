@@ -0,0 +1,135 @@
+/*
+Package main provides daily summary reports generated from the daily logs.
+Summaries are written as Markdown next to the logs they are built from.
+*/
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// summaryPath returns the path of the summary file for the given day
+func summaryPath(day time.Time) string {
+	return filepath.Join("logs", fmt.Sprintf("summary_%s.md", day.Format("2006-01-02")))
+}
+
+// buildDailySummary renders a Markdown recap of the findings logged on a day.
+// Price drops and shipping changes of known items are listed separately and don't count as new items.
+func buildDailySummary(day time.Time, findings []SavedItem) string {
+	var newItems, drops, shipping []SavedItem
+	for _, saved := range findings {
+		if saved.PreviousPrice != 0 {
+			drops = append(drops, saved)
+		}
+		if saved.PreviousShipping != nil {
+			shipping = append(shipping, saved)
+		}
+		if saved.isNew() {
+			newItems = append(newItems, saved)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# baycheck summary for %s\n\n", day.Format("2006-01-02"))
+	fmt.Fprintf(&b, "%d new items found.\n", len(newItems))
+	if len(drops) > 0 {
+		fmt.Fprintf(&b, "%d price drops.\n", len(drops))
+	}
+	if len(shipping) > 0 {
+		fmt.Fprintf(&b, "%d shipping changes.\n", len(shipping))
+	}
+	if len(newItems) == 0 {
+		writePriceDrops(&b, drops)
+		writeShippingChanges(&b, shipping)
+		return b.String()
+	}
+
+	byQuery := make(map[string][]SavedItem)
+	for _, saved := range newItems {
+		byQuery[saved.QueryTerm] = append(byQuery[saved.QueryTerm], saved)
+	}
+	queries := make([]string, 0, len(byQuery))
+	for query := range byQuery {
+		queries = append(queries, query)
+	}
+	sort.Strings(queries)
+
+	b.WriteString("\n| Query | New items | Cheapest | Most watched |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, query := range queries {
+		items := byQuery[query]
+		cheapest, mostWatched := items[0].Item, items[0].Item
+		for _, saved := range items[1:] {
+			item := saved.Item
+			if item.PriceValue >= 0 && (cheapest.PriceValue < 0 || item.PriceValue < cheapest.PriceValue) {
+				cheapest = item
+			}
+			if item.Watchers > mostWatched.Watchers {
+				mostWatched = item
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %d | [%.2f](%s) | [%d watchers](%s) |\n",
+			escapeMarkdownCell(query),
+			len(items),
+			cheapest.PriceValue, cheapest.URL,
+			mostWatched.Watchers, mostWatched.URL)
+	}
+
+	for _, query := range queries {
+		fmt.Fprintf(&b, "\n## %s\n\n", query)
+		for _, saved := range byQuery[query] {
+			fmt.Fprintf(&b, "- [%s](%s) - %s\n", escapeMarkdownCell(saved.Item.Title), saved.Item.URL, saved.Item.Price)
+		}
+	}
+	writePriceDrops(&b, drops)
+	writeShippingChanges(&b, shipping)
+	return b.String()
+}
+
+// writePriceDrops appends a section listing the price drops with their old and new price
+func writePriceDrops(b *strings.Builder, drops []SavedItem) {
+	if len(drops) == 0 {
+		return
+	}
+	b.WriteString("\n## Price drops\n\n")
+	for _, saved := range drops {
+		fmt.Fprintf(b, "- [%s](%s) - %.2f -> %.2f (%s)\n", escapeMarkdownCell(saved.Item.Title), saved.Item.URL,
+			saved.PreviousPrice, saved.Item.PriceValue, escapeMarkdownCell(saved.QueryTerm))
+	}
+}
+
+// writeShippingChanges appends a section listing the shipping changes with their old and new cost
+func writeShippingChanges(b *strings.Builder, changes []SavedItem) {
+	if len(changes) == 0 {
+		return
+	}
+	b.WriteString("\n## Shipping changes\n\n")
+	for _, saved := range changes {
+		fmt.Fprintf(b, "- [%s](%s) - %.2f -> %.2f (%s)\n", escapeMarkdownCell(saved.Item.Title), saved.Item.URL,
+			*saved.PreviousShipping, saved.Item.ShippingCost, escapeMarkdownCell(saved.QueryTerm))
+	}
+}
+
+// escapeMarkdownCell keeps titles from breaking table cells and links
+func escapeMarkdownCell(text string) string {
+	return strings.NewReplacer("|", "\\|", "[", "\\[", "]", "\\]").Replace(text)
+}
+
+// writeDailySummary generates the summary file for a day from its daily log
+func writeDailySummary(day time.Time) (string, error) {
+	findings, err := readDailyLog(day)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if err := os.MkdirAll("logs", 0755); err != nil {
+		return "", err
+	}
+	path := summaryPath(day)
+	return path, os.WriteFile(path, []byte(buildDailySummary(day, findings)), 0644)
+}
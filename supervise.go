@@ -0,0 +1,64 @@
+/*
+Package main provides the supervision of the search workers.
+A panic while running a search, e.g. on an unexpected listing layout, is
+recovered and the search is run again after a backoff instead of taking
+down the whole monitor.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// Restarts of a panicking search in one round, after that it waits for the next round
+const maxSearchRestarts = 3
+
+// searchRestartBackoff spaces the restarts of a panicking search
+var searchRestartBackoff BackoffStrategy = ExponentialBackoff{Base: 5 * time.Second, Max: time.Minute}
+
+// runRecovered runs fn and returns a panic it raised as an error including the stack trace
+func runRecovered(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	fn()
+	return nil
+}
+
+// superviseSearch runs a search and restarts it after a backoff if it panics, at most
+// maxSearchRestarts times. It reports whether the search finished without panicking.
+func superviseSearch(ctx context.Context, query string, backoff BackoffStrategy, metrics *Metrics, run func()) bool {
+	for restarts := 0; ; restarts++ {
+		err := runRecovered(run)
+		if err == nil {
+			return true
+		}
+		if restarts >= maxSearchRestarts || ctx.Err() != nil {
+			message := fmt.Sprintf("Search crashed, giving up until the next round: %v", err)
+			if jsonLogs {
+				logError(query, message)
+			} else {
+				log.Printf("Query '%s': %s", query, message)
+			}
+			return false
+		}
+
+		delay := backoff.NextDelay(restarts)
+		message := fmt.Sprintf("Search crashed, restarting in %s: %v", delay, err)
+		if jsonLogs {
+			logError(query, message)
+		} else {
+			log.Printf("Query '%s': %s", query, message)
+		}
+		metrics.observeRestart(query)
+		if !sleepContext(ctx, delay) {
+			return false
+		}
+	}
+}
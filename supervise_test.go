@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSuperviseSearchRestartsAfterPanic(t *testing.T) {
+	metrics := NewMetrics()
+	runs := 0
+	ok := superviseSearch(context.Background(), "switch", ConstantBackoff{}, metrics, func() {
+		runs++
+		if runs < 3 {
+			var items []Item
+			_ = items[runs] // an index out of range like a selector edge case
+		}
+	})
+	if !ok || runs != 3 {
+		t.Fatalf("superviseSearch = %v after %d runs, want true after 3", ok, runs)
+	}
+	if got := testutil.ToFloat64(metrics.restarts.WithLabelValues("switch")); got != 2 {
+		t.Errorf("restarts metric = %v, want 2", got)
+	}
+}
+
+func TestSuperviseSearchGivesUp(t *testing.T) {
+	runs := 0
+	ok := superviseSearch(context.Background(), "switch", ConstantBackoff{}, nil, func() {
+		runs++
+		panic("always")
+	})
+	if ok || runs != maxSearchRestarts+1 {
+		t.Errorf("superviseSearch = %v after %d runs, want false after %d", ok, runs, maxSearchRestarts+1)
+	}
+}
+
+func TestRunRecoveredIncludesStack(t *testing.T) {
+	err := runRecovered(func() { panic("selector edge case") })
+	if err == nil || !strings.Contains(err.Error(), "selector edge case") || !strings.Contains(err.Error(), "goroutine") {
+		t.Errorf("runRecovered = %v, want the panic value and a stack trace", err)
+	}
+	if err := runRecovered(func() {}); err != nil {
+		t.Errorf("runRecovered without a panic = %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+/*
+Package main provides Telegram notifications through a bot.
+Items found in a cycle are collected and sent when the cycle ends, one message
+per item with a button opening the listing, or a single list for larger batches.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Batches with more items than this are sent as a single list
+	telegramBatchSize = 3
+	// Stay below Telegram's limit of 20 messages per minute to the same group
+	telegramMessagesPerMinute = 20
+	// Telegram rejects messages longer than 4096 characters
+	telegramMaxMessageLength = 4000
+)
+
+/*
+TelegramConfig holds the bot token from @BotFather and the chat to post to.
+ChatID is the numeric chat id or "@channelname" and may be given as a number or a string.
+*/
+type TelegramConfig struct {
+	BotToken string         `json:"bot_token"`
+	ChatID   telegramChatID `json:"chat_id"`
+}
+
+// telegramChatID accepts chat ids written as JSON numbers or strings
+type telegramChatID string
+
+// UnmarshalJSON reads the chat id from a number or a string
+func (c *telegramChatID) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err == nil {
+		*c = telegramChatID(number)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("chat_id must be a number or a string")
+	}
+	*c = telegramChatID(s)
+	return nil
+}
+
+// validate checks that the settings needed to send a message are present
+func (t *TelegramConfig) validate() error {
+	var problems []error
+	if t.BotToken == "" {
+		problems = append(problems, errors.New("telegram: bot_token is empty"))
+	}
+	if t.ChatID == "" {
+		problems = append(problems, errors.New("telegram: chat_id is empty"))
+	}
+	return errors.Join(problems...)
+}
+
+/*
+batchNotifier is a Notifier collecting items until Flush is called at the end of a cycle.
+*/
+type batchNotifier interface {
+	Notifier
+	Flush() error
+}
+
+// flushNotifier sends the items collected by a batching notifier, if the notifier batches
+func flushNotifier(notifier Notifier) error {
+	if batch, ok := notifier.(batchNotifier); ok {
+		return batch.Flush()
+	}
+	return nil
+}
+
+/*
+TelegramNotifier sends new items and changes to a Telegram chat through the Bot API,
+formatting single items with the Telegram template.
+Notify only queues the item, Flush sends the queued items.
+*/
+type TelegramNotifier struct {
+	Config    *TelegramConfig
+	Client    *http.Client
+	Templates *notificationTemplates
+	limiter   *RateLimiter
+
+	mu      sync.Mutex
+	pending []SavedItem
+}
+
+// newTelegramNotifier creates a notifier rate limited to Telegram's message limits
+func newTelegramNotifier(config *TelegramConfig, client *http.Client) *TelegramNotifier {
+	return &TelegramNotifier{Config: config, Client: client, limiter: NewRateLimiter(telegramMessagesPerMinute)}
+}
+
+// Notify queues a new item for the next Flush
+func (t *TelegramNotifier) Notify(item Item, query string) error {
+	t.queue(newSavedItem(item, query))
+	return nil
+}
+
+// NotifyChange queues a price drop or shipping change for the next Flush
+func (t *TelegramNotifier) NotifyChange(saved SavedItem) error {
+	t.queue(saved)
+	return nil
+}
+
+// queue adds an entry to the items sent by the next Flush
+func (t *TelegramNotifier) queue(saved SavedItem) {
+	t.mu.Lock()
+	t.pending = append(t.pending, saved)
+	t.mu.Unlock()
+}
+
+// Flush sends the queued items, each as its own message with a button to the listing
+// or, for more than a few items, as a list of links split over as few messages as possible
+func (t *TelegramNotifier) Flush() error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+
+	var errs []error
+	if len(pending) <= telegramBatchSize {
+		for _, p := range pending {
+			button := map[string]any{
+				"inline_keyboard": [][]map[string]string{{{"text": "Open listing", "url": p.Item.URL}}},
+			}
+			text, err := t.itemText(p)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err := t.send(text, button); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	newItems, drops, shipping := 0, 0, 0
+	for _, p := range pending {
+		if p.isNew() {
+			newItems++
+		}
+		if p.PreviousPrice != 0 {
+			drops++
+		}
+		if p.PreviousShipping != nil {
+			shipping++
+		}
+	}
+	counts := []string{fmt.Sprintf("%d new items", newItems)}
+	if drops > 0 {
+		counts = append(counts, fmt.Sprintf("%d price drops", drops))
+	}
+	if shipping > 0 {
+		counts = append(counts, fmt.Sprintf("%d shipping changes", shipping))
+	}
+	header := fmt.Sprintf("<b>%s</b>\n", strings.Join(counts, ", "))
+	message := header
+	for _, p := range pending {
+		price := html.EscapeString(strings.TrimSpace(p.Item.Price))
+		if p.PreviousPrice != 0 {
+			price = fmt.Sprintf("price drop %.2f -> %s", p.PreviousPrice, price)
+		}
+		if p.PreviousShipping != nil {
+			price += fmt.Sprintf(", shipping %.2f -> %.2f", *p.PreviousShipping, p.Item.ShippingCost)
+		}
+		entry := fmt.Sprintf("\n• <a href=\"%s\">%s</a>\n%s, %d watchers%s, for \"%s\"\n",
+			html.EscapeString(p.Item.URL), html.EscapeString(p.Item.Title),
+			price, p.Item.Watchers, telegramTimeLeft(p.Item), html.EscapeString(p.QueryTerm))
+		if len(message)+len(entry) > telegramMaxMessageLength && message != header {
+			if err := t.send(message, nil); err != nil {
+				errs = append(errs, err)
+			}
+			message = header
+		}
+		message += entry
+	}
+	if err := t.send(message, nil); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// itemText formats a single new item, price drop or shipping change as an HTML message
+// with the Telegram template, the built-in one if Templates is nil
+func (t *TelegramNotifier) itemText(p SavedItem) (string, error) {
+	templates := t.Templates
+	if templates == nil {
+		templates = builtinTemplates
+	}
+	return executeTemplate(templates.Telegram, p)
+}
+
+// telegramTimeLeft returns ", <time left>" for auctions and an empty string otherwise
+func telegramTimeLeft(item Item) string {
+	if item.TimeLeft == "" {
+		return ""
+	}
+	return ", " + html.EscapeString(item.TimeLeft)
+}
+
+// send posts an HTML message to the chat, waiting for the rate limit first
+func (t *TelegramNotifier) send(text string, replyMarkup any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := t.limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	payload := map[string]any{
+		"chat_id":                  string(t.Config.ChatID),
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+	if replyMarkup != nil {
+		payload["reply_markup"] = replyMarkup
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	endpoint := "https://api.telegram.org/bot" + t.Config.BotToken + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("telegram: invalid bot_token")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		// The request URL contains the bot token, so it is left out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = "https://api.telegram.org/bot<bot_token>/sendMessage"
+		}
+		return fmt.Errorf("telegram: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || !result.OK {
+		if result.Description != "" {
+			return fmt.Errorf("telegram returned status %s: %s", resp.Status, result.Description)
+		}
+		return fmt.Errorf("telegram returned status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// failingTransport fails every request with err
+type failingTransport struct {
+	err error
+}
+
+func (f failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, f.err
+}
+
+func TestTelegramSendErrorHidesToken(t *testing.T) {
+	cause := errors.New("connection refused")
+	notifier := newTelegramNotifier(&TelegramConfig{BotToken: "123:secret", ChatID: "42"},
+		&http.Client{Transport: failingTransport{cause}})
+
+	err := notifier.send("hello", nil)
+	if err == nil {
+		t.Fatal("send succeeded without a connection")
+	}
+	if !errors.Is(err, cause) || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("error %q doesn't wrap the cause", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error %q contains the bot token", err)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Package main provides the notification templates of the channels.
+Each channel has a built-in template that the templates setting can replace
+with a Go template over the SavedItem being notified about.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// The built-in template of single Telegram messages, in Telegram's HTML
+const defaultTelegramTemplate = `<b>{{if .PreviousPrice}}Price drop{{else if .PreviousShipping}}Shipping change{{else}}New match{{end}} for "{{html .QueryTerm}}"</b>
+{{html .Item.Title}}
+
+Price: {{html (trim .Item.Price)}}
+Watchers: {{.Item.Watchers}}
+{{- if .PreviousPrice}}
+Previous price: {{printf "%.2f" .PreviousPrice}}{{end}}
+{{- with .PreviousShipping}}
+Shipping: {{printf "%.2f" $.Item.ShippingCost}}, previously {{printf "%.2f" (deref .)}}{{end}}
+{{- if .Item.TimeLeft}}
+Time left: {{html .Item.TimeLeft}}{{end}}`
+
+// The built-in template of the email body
+const defaultEmailTemplate = `<html><body>
+{{if .PreviousPrice}}<h2>Price drop for "{{.QueryTerm}}"</h2>{{else if .PreviousShipping}}<h2>Shipping change for "{{.QueryTerm}}"</h2>{{else}}<h2>New match for "{{.QueryTerm}}"</h2>{{end}}
+<p><a href="{{.Item.URL}}">{{.Item.Title}}</a></p>
+<table>
+<tr><td>Price</td><td>{{.Item.Price}}</td></tr>
+{{if .PreviousPrice}}<tr><td>Previous price</td><td>{{printf "%.2f" .PreviousPrice}}</td></tr>
+{{end}}{{with .PreviousShipping}}<tr><td>Shipping</td><td>{{printf "%.2f" $.Item.ShippingCost}}</td></tr>
+<tr><td>Previous shipping</td><td>{{printf "%.2f" (deref .)}}</td></tr>
+{{end}}{{if .Item.TimeLeft}}<tr><td>Time left</td><td>{{.Item.TimeLeft}}</td></tr>
+{{end}}<tr><td>Watchers</td><td>{{.Item.Watchers}}</td></tr>
+</table>
+</body></html>
+`
+
+// The built-in template of the email subject
+const defaultEmailSubjectTemplate = `baycheck: {{if .PreviousPrice}}price drop {{.Item.Title}} ({{printf "%.2f" .PreviousPrice}} -> {{trim .Item.Price}})
+{{- else if .PreviousShipping}}shipping change {{.Item.Title}} ({{printf "%.2f" (deref .PreviousShipping)}} -> {{printf "%.2f" .Item.ShippingCost}})
+{{- else}}{{.Item.Title}} ({{trim .Item.Price}}){{end}}`
+
+// Channels whose template can be replaced; the webhook has no built-in template
+// and sends the versioned JSON payload unless one is configured
+var templateChannels = []string{"telegram", "email", "email_subject", "webhook"}
+
+/*
+notificationTemplates holds the parsed template of each channel.
+Webhook is nil unless configured.
+*/
+type notificationTemplates struct {
+	Telegram     *texttemplate.Template
+	Email        *htmltemplate.Template
+	EmailSubject *texttemplate.Template
+	Webhook      *texttemplate.Template
+}
+
+// templateFuncs returns the helper functions of the templates, with link
+// writing a link in the markup of the channel
+func templateFuncs(link any) map[string]any {
+	return map[string]any{
+		// money formats an amount with its currency, e.g. "EUR 229.99"
+		"money": func(amount float64, currency string) string {
+			if currency == "" {
+				return fmt.Sprintf("%.2f", amount)
+			}
+			return fmt.Sprintf("%s %.2f", currency, amount)
+		},
+		// shipping describes the shipping cost of an item, e.g. "free" or "EUR 4.99"
+		"shipping": func(item Item) string {
+			switch {
+			case item.ShippingCost < 0:
+				return "unknown"
+			case item.ShippingCost == 0:
+				return "free"
+			case item.Currency == "":
+				return fmt.Sprintf("%.2f", item.ShippingCost)
+			}
+			return fmt.Sprintf("%s %.2f", item.Currency, item.ShippingCost)
+		},
+		// json encodes a value as JSON, e.g. to quote titles in webhook bodies
+		"json": func(v any) (string, error) {
+			var b bytes.Buffer
+			encoder := json.NewEncoder(&b)
+			encoder.SetEscapeHTML(false)
+			err := encoder.Encode(v)
+			return strings.TrimSuffix(b.String(), "\n"), err
+		},
+		"deref": func(f *float64) float64 {
+			if f == nil {
+				return 0
+			}
+			return *f
+		},
+		"trim": strings.TrimSpace,
+		"link": link,
+	}
+}
+
+// parseTextTemplate parses a text template for a channel with the helper functions
+func parseTextTemplate(channel, text string, link any) (*texttemplate.Template, error) {
+	tmpl, err := texttemplate.New(channel).Funcs(templateFuncs(link)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("templates: %s: %w", channel, err)
+	}
+	return tmpl, nil
+}
+
+// parseTemplates parses the configured templates keyed by channel, using the built-in
+// template of channels that aren't configured
+func parseTemplates(config map[string]string) (*notificationTemplates, error) {
+	for channel := range config {
+		known := false
+		for _, c := range templateChannels {
+			known = known || c == channel
+		}
+		if !known {
+			return nil, fmt.Errorf("templates: unknown channel %q, expected one of %s", channel, strings.Join(templateChannels, ", "))
+		}
+	}
+	text := func(channel, fallback string) string {
+		if t, ok := config[channel]; ok {
+			return t
+		}
+		return fallback
+	}
+
+	// Telegram reads HTML, the webhook usually Markdown as in Slack and Discord
+	telegramLink := func(url, text string) string {
+		return fmt.Sprintf(`<a href="%s">%s</a>`, texttemplate.HTMLEscapeString(url), texttemplate.HTMLEscapeString(text))
+	}
+	markdownLink := func(url, text string) string {
+		return fmt.Sprintf("[%s](%s)", text, url)
+	}
+	// Only web links, as the email link bypasses the URL checks of html/template
+	emailLink := func(url, text string) htmltemplate.HTML {
+		if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+			return htmltemplate.HTML(htmltemplate.HTMLEscapeString(text))
+		}
+		return htmltemplate.HTML(fmt.Sprintf(`<a href="%s">%s</a>`,
+			htmltemplate.HTMLEscapeString(url), htmltemplate.HTMLEscapeString(text)))
+	}
+
+	var templates notificationTemplates
+	var err error
+	if templates.Telegram, err = parseTextTemplate("telegram", text("telegram", defaultTelegramTemplate), telegramLink); err != nil {
+		return nil, err
+	}
+	if templates.EmailSubject, err = parseTextTemplate("email_subject", text("email_subject", defaultEmailSubjectTemplate), markdownLink); err != nil {
+		return nil, err
+	}
+	if webhook, ok := config["webhook"]; ok {
+		if templates.Webhook, err = parseTextTemplate("webhook", webhook, markdownLink); err != nil {
+			return nil, err
+		}
+	}
+	templates.Email, err = htmltemplate.New("email").Funcs(templateFuncs(emailLink)).Parse(text("email", defaultEmailTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("templates: email: %w", err)
+	}
+	return &templates, nil
+}
+
+// builtinTemplates are used by notifiers created without templates
+var builtinTemplates = func() *notificationTemplates {
+	templates, err := parseTemplates(nil)
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}()
+
+// executeTemplate renders a text template for an entry
+func executeTemplate(tmpl *texttemplate.Template, saved SavedItem) (string, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, saved); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
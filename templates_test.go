@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// templateItem returns a price drop of an item with characters that need escaping
+func templateItem() SavedItem {
+	return SavedItem{
+		Item: Item{ID: "1", Title: "Switch <OLED> & Dock", Price: " EUR 229,99 ", PriceValue: 229.99,
+			Currency: "EUR", ShippingCost: 4.99, Watchers: 7, SellerName: "retro_shop", URL: "https://www.ebay.de/itm/1"},
+		Found:         time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		QueryTerm:     "switch",
+		PreviousPrice: 249.99,
+	}
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	saved := templateItem()
+
+	text, err := executeTemplate(builtinTemplates.Telegram, saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<b>Price drop for \"switch\"</b>\nSwitch &lt;OLED&gt; &amp; Dock\n\nPrice: EUR 229,99\nWatchers: 7\nPrevious price: 249.99"
+	if text != want {
+		t.Errorf("telegram message =\n%s\nwant\n%s", text, want)
+	}
+
+	subject, err := executeTemplate(builtinTemplates.EmailSubject, saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "baycheck: price drop Switch <OLED> & Dock (249.99 -> EUR 229,99)"; subject != want {
+		t.Errorf("email subject = %q, want %q", subject, want)
+	}
+
+	var body strings.Builder
+	if err := builtinTemplates.Email.Execute(&body, saved); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body.String(), "Switch &lt;OLED&gt; &amp; Dock") || !strings.Contains(body.String(), "<td>249.99</td>") {
+		t.Errorf("email body doesn't escape the title or lacks the previous price:\n%s", body.String())
+	}
+	if builtinTemplates.Webhook != nil {
+		t.Error("the webhook has a built-in template, it should send the JSON payload")
+	}
+}
+
+func TestConfiguredTemplates(t *testing.T) {
+	templates, err := parseTemplates(map[string]string{
+		"telegram": `{{link .Item.URL .Item.Title}} {{money .Item.PriceValue .Item.Currency}} + {{shipping .Item}}, by {{html .Item.SellerName}}`,
+		"webhook":  `{"content": {{json (printf "%s: %s" .QueryTerm (link .Item.URL .Item.Title))}}}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := templateItem()
+
+	text, err := executeTemplate(templates.Telegram, saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<a href="https://www.ebay.de/itm/1">Switch &lt;OLED&gt; &amp; Dock</a> EUR 229.99 + EUR 4.99, by retro_shop`; text != want {
+		t.Errorf("telegram message = %s, want %s", text, want)
+	}
+	// Channels without a configured template keep the built-in one
+	if templates.EmailSubject == nil || templates.Email == nil {
+		t.Error("unconfigured channels have no template")
+	}
+
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+	notifier := &WebhookNotifier{URL: server.URL, Client: server.Client(), Template: templates.Webhook}
+	if err := notifier.NotifyChange(saved); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"content": "switch: [Switch <OLED> & Dock](https://www.ebay.de/itm/1)"}`; body != want {
+		t.Errorf("webhook body = %s, want %s", body, want)
+	}
+
+	for name, config := range map[string]map[string]string{
+		"unknown channel": {"discord": "{{.Item.Title}}"},
+		"parse error":     {"telegram": "{{.Item.Title"},
+		"unknown func":    {"email": "{{price .Item}}"},
+	} {
+		if _, err := parseTemplates(config); err == nil {
+			t.Errorf("%s: parseTemplates accepted %v", name, config)
+		}
+	}
+}
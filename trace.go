@@ -0,0 +1,66 @@
+/*
+Package main provides optional timing diagnostics for HTTP requests.
+Timings break a request down into DNS, connect, TLS and server time.
+*/
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// requestTimings records the phases of a single HTTP request
+type requestTimings struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+// traceRequest attaches a ClientTrace to the request that fills in the returned timings
+func traceRequest(req *http.Request) (*http.Request, *requestTimings) {
+	timings := &requestTimings{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { timings.dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { timings.dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { timings.connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { timings.connectDone = time.Now() },
+		TLSHandshakeStart:    func() { timings.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { timings.tlsDone = time.Now() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { timings.wroteRequest = time.Now() },
+		GotFirstResponseByte: func() { timings.firstByte = time.Now() },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), timings
+}
+
+// span returns the duration between two points, or zero if the phase didn't happen
+// (e.g. DNS and TLS are skipped on reused connections)
+func span(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// phase is span rounded to milliseconds for logging
+func phase(start, end time.Time) time.Duration {
+	return span(start, end).Round(time.Millisecond)
+}
+
+// String formats the timings as a single log-friendly line
+func (t *requestTimings) String() string {
+	return fmt.Sprintf("dns=%s connect=%s tls=%s server=%s total=%s",
+		phase(t.dnsStart, t.dnsDone),
+		phase(t.connectStart, t.connectDone),
+		phase(t.tlsStart, t.tlsDone),
+		phase(t.wroteRequest, t.firstByte),
+		phase(t.start, time.Now()))
+}